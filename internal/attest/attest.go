@@ -0,0 +1,303 @@
+// Package attest implements the attest command, which records and checks what a
+// published .vsix was built from.
+//
+// Usage:
+//
+//	ovsx-setup attest create --vsix <file> [--sbom <file>] [--out <file>]
+//	ovsx-setup attest verify --vsix <file> [--sbom <file>] [--statement <file>]
+//
+// create writes an in-toto statement with a SLSA provenance predicate next to the .vsix.
+// Its subjects are the .vsix and the SBOM, identified by their SHA-256, and it records the
+// fork repository and commit, the workflow that built them, and the upstream repository
+// and the upstream commit the fork is based on. In GitHub Actions these come from the
+// workflow's environment; elsewhere from the local clone.
+//
+// verify checks that the .vsix (and SBOM) match the digests in the statement, and prints
+// where they were built from.
+package attest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/verify"
+)
+
+const (
+	// StatementType is the in-toto statement type.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// ProvenanceType is the SLSA provenance predicate type.
+	ProvenanceType = "https://slsa.dev/provenance/v1"
+	// BuildType identifies builds made by the release workflow.
+	BuildType = "https://github.com/timsexperiments/ovsx-fork-tools/release@v1"
+)
+
+// Statement is an in-toto statement about the built files.
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []Resource `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Resource is a file or revision identified by its digests.
+type Resource struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is a SLSA provenance predicate.
+type Provenance struct {
+	BuildDefinition struct {
+		BuildType            string            `json:"buildType"`
+		ExternalParameters   map[string]string `json:"externalParameters"`
+		ResolvedDependencies []Resource        `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			InvocationID string `json:"invocationId,omitempty"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+// Source describes where a build comes from.
+type Source struct {
+	Repository string
+	Commit     string
+	Ref        string
+	// Workflow is the workflow reference, e.g. "owner/repo/.github/workflows/release.yml@refs/tags/v1.0.0".
+	Workflow string
+	// Run is the URL of the workflow run.
+	Run            string
+	UpstreamURL    string
+	UpstreamCommit string
+}
+
+func Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: attest create|verify [flags]")
+	}
+	switch args[0] {
+	case "create":
+		return runCreate(args[1:])
+	case "verify":
+		return runVerify(args[1:])
+	}
+	return fmt.Errorf("unknown attest command %q (expected create or verify)", args[0])
+}
+
+func runCreate(args []string) error {
+	fs := flag.NewFlagSet("attest create", flag.ContinueOnError)
+	vsix := fs.String("vsix", "", "The packaged .vsix")
+	sbom := fs.String("sbom", "", "The SBOM generated for the build")
+	out := fs.String("out", "", "Where to write the statement (default: <vsix>.intoto.json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *vsix == "" {
+		return fmt.Errorf("--vsix is required")
+	}
+	if *out == "" {
+		*out = StatementPath(*vsix)
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	src, err := DetectSource(cfg)
+	if err != nil {
+		return err
+	}
+
+	files := []string{*vsix}
+	if *sbom != "" {
+		files = append(files, *sbom)
+	}
+	st, err := New(files, src)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *out)
+	return nil
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("attest verify", flag.ContinueOnError)
+	vsix := fs.String("vsix", "", "The .vsix to verify")
+	sbom := fs.String("sbom", "", "Also verify this SBOM")
+	statement := fs.String("statement", "", "The statement (default: <vsix>.intoto.json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *vsix == "" {
+		return fmt.Errorf("--vsix is required")
+	}
+	if *statement == "" {
+		*statement = StatementPath(*vsix)
+	}
+
+	data, err := os.ReadFile(*statement)
+	if err != nil {
+		return err
+	}
+	var st Statement
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("invalid statement %s: %w", *statement, err)
+	}
+
+	files := []string{*vsix}
+	if *sbom != "" {
+		files = append(files, *sbom)
+	}
+	if err := Verify(&st, files); err != nil {
+		return err
+	}
+	st.WriteText(os.Stdout)
+	return nil
+}
+
+// StatementPath returns the default statement path of a .vsix.
+func StatementPath(vsix string) string {
+	return strings.TrimSuffix(vsix, ".vsix") + ".intoto.json"
+}
+
+// DetectSource describes the build from the GitHub Actions environment, falling back to
+// the local clone, and fetches upstream to find the upstream commit HEAD is based on.
+func DetectSource(cfg *config.Config) (*Source, error) {
+	src := &Source{
+		Repository: os.Getenv("GITHUB_REPOSITORY"),
+		Commit:     os.Getenv("GITHUB_SHA"),
+		Ref:        os.Getenv("GITHUB_REF"),
+		Workflow:   os.Getenv("GITHUB_WORKFLOW_REF"),
+	}
+	if server, id := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_RUN_ID"); server != "" && id != "" {
+		src.Run = fmt.Sprintf("%s/%s/actions/runs/%s/attempts/%s", server, src.Repository, id, os.Getenv("GITHUB_RUN_ATTEMPT"))
+		src.Repository = server + "/" + src.Repository
+	}
+	if src.Repository == "" {
+		src.Repository, _ = git.Output("remote", "get-url", "origin")
+	}
+	if src.Commit == "" {
+		var err error
+		if src.Commit, err = git.Output("rev-parse", "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	url, err := sync.DetectURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	target, err := sync.Fetch(cfg, url)
+	if err != nil {
+		return nil, err
+	}
+	if src.UpstreamCommit, _, err = sync.Base(cfg, target); err != nil {
+		return nil, err
+	}
+	src.UpstreamURL = url
+	return src, nil
+}
+
+// New creates a statement about files built from src.
+func New(files []string, src *Source) (*Statement, error) {
+	st := &Statement{Type: StatementType, PredicateType: ProvenanceType}
+	for _, f := range files {
+		sum, err := verify.FileSHA256(f)
+		if err != nil {
+			return nil, err
+		}
+		st.Subject = append(st.Subject, Resource{Name: filepath.Base(f), Digest: map[string]string{"sha256": sum}})
+	}
+
+	def := &st.Predicate.BuildDefinition
+	def.BuildType = BuildType
+	def.ExternalParameters = map[string]string{"repository": src.Repository, "ref": src.Ref, "workflow": src.Workflow}
+	def.ResolvedDependencies = []Resource{
+		{Name: "source", URI: "git+" + src.Repository, Digest: map[string]string{"gitCommit": src.Commit}},
+		{Name: "upstream", URI: "git+" + src.UpstreamURL, Digest: map[string]string{"gitCommit": src.UpstreamCommit}},
+	}
+	st.Predicate.RunDetails.Builder.ID = src.Workflow
+	st.Predicate.RunDetails.Metadata.InvocationID = src.Run
+	return st, nil
+}
+
+// Verify checks that each file is a subject of the statement with the same SHA-256.
+func Verify(st *Statement, files []string) error {
+	if st.Type != StatementType || st.PredicateType != ProvenanceType {
+		return fmt.Errorf("not a provenance statement (type %q, predicate %q)", st.Type, st.PredicateType)
+	}
+	for _, f := range files {
+		sum, err := verify.FileSHA256(f)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(f)
+		subject := st.subject(name)
+		if subject == nil {
+			return fmt.Errorf("the statement does not cover %s", name)
+		}
+		if subject.Digest["sha256"] != sum {
+			return fmt.Errorf("%s does not match the statement (sha256 %s, expected %s)", name, sum, subject.Digest["sha256"])
+		}
+	}
+	return nil
+}
+
+func (st *Statement) subject(name string) *Resource {
+	for i := range st.Subject {
+		if st.Subject[i].Name == name {
+			return &st.Subject[i]
+		}
+	}
+	return nil
+}
+
+// WriteText writes the verified subjects and where they were built from.
+func (st *Statement) WriteText(w io.Writer) {
+	for _, s := range st.Subject {
+		fmt.Fprintf(w, "Verified %s (sha256 %s)\n", s.Name, s.Digest["sha256"])
+	}
+	for _, d := range st.Predicate.BuildDefinition.ResolvedDependencies {
+		fmt.Fprintf(w, "%s: %s@%s\n", capitalize(d.Name), strings.TrimPrefix(d.URI, "git+"), d.Digest["gitCommit"])
+	}
+	if wf := st.Predicate.BuildDefinition.ExternalParameters["workflow"]; wf != "" {
+		fmt.Fprintf(w, "Workflow: %s\n", wf)
+	}
+	if run := st.Predicate.RunDetails.Metadata.InvocationID; run != "" {
+		fmt.Fprintf(w, "Run: %s\n", run)
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
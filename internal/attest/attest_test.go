@@ -0,0 +1,91 @@
+package attest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/attest"
+)
+
+func TestStatement(t *testing.T) {
+	dir := t.TempDir()
+	vsix := filepath.Join(dir, "ext-1.2.0.vsix")
+	sbom := filepath.Join(dir, "ext-1.2.0.sbom.json")
+	if err := os.WriteFile(vsix, []byte("vsix"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sbom, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &attest.Source{
+		Repository:     "https://github.com/fork/ext",
+		Commit:         "1111111",
+		Ref:            "refs/tags/v1.2.0",
+		Workflow:       "fork/ext/.github/workflows/ovsx-fork-tools-release.yml@refs/heads/main",
+		Run:            "https://github.com/fork/ext/actions/runs/1/attempts/1",
+		UpstreamURL:    "https://github.com/owner/ext.git",
+		UpstreamCommit: "2222222",
+	}
+	st, err := attest.New([]string{vsix, sbom}, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Round-trip through JSON, as the workflow writes the statement to a file.
+	data, err := json.Marshal(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read attest.Statement
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if err := attest.Verify(&read, []string{vsix, sbom}); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	read.WriteText(&out)
+	for _, want := range []string{
+		"Verified ext-1.2.0.vsix (sha256 ",
+		"Source: https://github.com/fork/ext@1111111",
+		"Upstream: https://github.com/owner/ext.git@2222222",
+		"Workflow: fork/ext/.github/workflows/ovsx-fork-tools-release.yml@refs/heads/main",
+		"Run: https://github.com/fork/ext/actions/runs/1/attempts/1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := os.WriteFile(vsix, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := attest.Verify(&read, []string{vsix}); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Verify() of a changed .vsix error = %v, want a mismatch", err)
+	}
+
+	other := filepath.Join(dir, "other-1.0.0.vsix")
+	if err := os.WriteFile(other, []byte("vsix"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := attest.Verify(&read, []string{other}); err == nil || !strings.Contains(err.Error(), "does not cover") {
+		t.Errorf("Verify() of another .vsix error = %v, want not covered", err)
+	}
+
+	read.PredicateType = "https://example.com/other"
+	if err := attest.Verify(&read, []string{sbom}); err == nil {
+		t.Error("Verify() accepted a statement of another predicate type")
+	}
+}
+
+func TestStatementPath(t *testing.T) {
+	if got := attest.StatementPath("dist/ext-1.2.0.vsix"); got != "dist/ext-1.2.0.intoto.json" {
+		t.Errorf("StatementPath() = %q", got)
+	}
+}
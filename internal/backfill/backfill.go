@@ -0,0 +1,246 @@
+// Package backfill implements the backfill command, which publishes upstream releases
+// that never reached OpenVSX under the fork's publisher.
+//
+// Usage:
+//
+//	ovsx-setup backfill [--dry-run] [--since <version>] [--registry <url>]
+//
+// It lists upstream's release tags, looks up the versions already published in the
+// publisher's namespace, and publishes each missing version from the oldest up: the tag
+// is checked out in a temporary worktree, the fork's name, branding fields, publisher and
+// version are written into its package.json, and the extension is built, packaged with
+// vsce, published with ovsx and verified against the registry. The OpenVSX token is read from OPEN_VSX_TOKEN.
+// With --dry-run it only lists the versions it would publish.
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/verify"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// Release is an upstream release missing from the registry.
+type Release struct {
+	Tag      string
+	Upstream version.Version
+	// Version is the fork version the release is published as.
+	Version string
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only list the versions that would be published")
+	since := fs.String("since", "", "Skip upstream versions lower than this one")
+	registry := fs.String("registry", openvsx.DefaultURL, "OpenVSX registry to publish to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if cfg.Publisher == "" {
+		return fmt.Errorf("no publisher is configured; run 'ovsx-setup --publisher <id>' first")
+	}
+
+	var min *version.Version
+	if *since != "" {
+		v, err := version.Parse(*since)
+		if err != nil {
+			return err
+		}
+		min = &v
+	}
+
+	token := os.Getenv("OPEN_VSX_TOKEN")
+	if token == "" && !*dryRun {
+		return fmt.Errorf("OPEN_VSX_TOKEN is not set")
+	}
+
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+
+	url, err := sync.DetectURL(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Fetching upstream %s\n", url)
+	if _, err := sync.Fetch(cfg, url); err != nil {
+		return err
+	}
+	tags, err := sync.Tags(cfg.UpstreamTagPattern)
+	if err != nil {
+		return err
+	}
+
+	// Backfilled releases are published under the fork's name, see Overrides
+	client := openvsx.New(*registry)
+	published := map[string]bool{}
+	ext, err := client.Extension(cfg.Publisher, m.Name, "")
+	switch {
+	case errors.Is(err, openvsx.ErrNotFound):
+		// Nothing was published yet
+	case err != nil:
+		return err
+	default:
+		for v := range ext.AllVersions {
+			published[v] = true
+		}
+	}
+
+	releases, err := Missing(cfg, tags, published, min)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		fmt.Fprintln(os.Stderr, "Every upstream release is published.")
+		return nil
+	}
+	for _, r := range releases {
+		fmt.Printf("%s\t%s\n", r.Tag, r.Version)
+	}
+	if *dryRun {
+		return nil
+	}
+
+	for i, r := range releases {
+		fmt.Fprintf(os.Stderr, "Publishing %s from %s (%d/%d)\n", r.Version, r.Tag, i+1, len(releases))
+		if err := Publish(cfg, client, r, token); err != nil {
+			return fmt.Errorf("failed to publish %s from %s: %w", r.Version, r.Tag, err)
+		}
+	}
+	return nil
+}
+
+// Missing returns the upstream tags whose fork version is not published, oldest first.
+// Tags that are not versions, pre-releases and versions lower than min are skipped.
+func Missing(cfg *config.Config, tags []string, published map[string]bool, min *version.Version) ([]Release, error) {
+	var releases []Release
+	for _, t := range tags {
+		v, err := sync.TagVersion(t)
+		if err != nil || v.Pre != "" || (min != nil && v.Compare(*min) < 0) {
+			continue
+		}
+		ver := v
+		if cfg.VersionScheme == version.SchemeRevision {
+			if ver, err = version.Fork(v, 0, cfg.Digits()); err != nil {
+				return nil, err
+			}
+		}
+		if !published[ver.String()] {
+			releases = append(releases, Release{Tag: t, Upstream: v, Version: ver.String()})
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool { return releases[i].Upstream.Compare(releases[j].Upstream) < 0 })
+	return releases, nil
+}
+
+// Overrides returns the package.json fields written over upstream's manifest for release r,
+// so it is published like the fork's own releases: the fork's name, the fields its
+// package.json conflict policy keeps (the branding fields by default), the publisher and
+// the fork version. The name is always the fork's, since it identifies the extension.
+func Overrides(cfg *config.Config, fork *manifest.Object, r Release) map[string]json.RawMessage {
+	fields := config.DefaultMergeFields
+	file := path.Join(tag.CleanPath(cfg.ExtensionDir()), manifest.FileName)
+	if p, ok := cfg.Policy(file); ok && p.Policy == config.PolicyJSONFieldMerge {
+		fields = p.MergeFields()
+	}
+
+	overrides := map[string]json.RawMessage{}
+	for _, field := range append([]string{"name"}, fields...) {
+		if value := fork.Get(field); value != nil {
+			overrides[field] = value
+		}
+	}
+	overrides["publisher"] = manifest.String(cfg.Publisher)
+	overrides["version"] = manifest.String(r.Version)
+	return overrides
+}
+
+// Publish builds, packages, publishes and verifies a release from a temporary worktree.
+func Publish(cfg *config.Config, client *openvsx.Client, r Release, token string) error {
+	dir, err := os.MkdirTemp("", "ovsx-backfill-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if _, err := git.Output("worktree", "add", "-q", "--detach", dir, "refs/upstream-tags/"+r.Tag); err != nil {
+		return err
+	}
+	defer git.Output("worktree", "remove", "--force", dir)
+
+	fork, err := manifest.ReadObject(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+	extDir := filepath.Join(dir, cfg.ExtensionDir())
+	if err := manifest.UpdateRaw(extDir, Overrides(cfg, fork, r)); err != nil {
+		return err
+	}
+	m, err := manifest.Read(extDir)
+	if err != nil {
+		return err
+	}
+
+	type step struct {
+		dir  string
+		args []string
+	}
+	// The release workflow's steps, with its pre-build and build commands when configured
+	steps := []step{{dir, []string{"pnpm", "install", "--frozen-lockfile"}}}
+	if cfg.PreBuildCommand != "" {
+		steps = append(steps, step{dir, []string{"sh", "-c", cfg.PreBuildCommand}})
+	}
+	switch {
+	case cfg.BuildCommand != "":
+		steps = append(steps, step{dir, []string{"sh", "-c", cfg.BuildCommand}})
+	case cfg.BuildScope == config.BuildScopeExtension:
+		steps = append(steps, step{dir, []string{"pnpm", "--filter", m.Name + "...", "run", "--if-present", "build"}})
+	default:
+		steps = append(steps, step{dir, []string{"pnpm", "-r", "run", "build"}})
+	}
+	vsix := fmt.Sprintf("%s-%s.vsix", m.Name, r.Version)
+	steps = append(steps,
+		step{extDir, append([]string{"pnpm", "dlx", "vsce", "package", "-o", vsix}, cfg.PackageArguments()...)},
+		step{extDir, []string{"pnpm", "dlx", "ovsx", "publish", vsix, "--registryUrl", client.URL, "-p", token}},
+	)
+
+	for _, step := range steps {
+		cmd := exec.Command(step.args[0], step.args[1:]...)
+		cmd.Dir = step.dir
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s: %w", step.args[0], step.args[1], err)
+		}
+	}
+
+	_, err = verify.Verify(client, verify.Options{
+		Namespace: cfg.Publisher, Name: m.Name, Version: r.Version, Vsix: filepath.Join(extDir, vsix),
+		Timeout: 5 * time.Minute, Interval: 10 * time.Second,
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package backfill_test
+
+import (
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/backfill"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+func TestMissing(t *testing.T) {
+	tags := []string{"v1.11.0-rc.1", "v1.10.0", "v1.2.0", "v1.0.1", "v1.0.0", "nightly"}
+	since := version.Version{Major: 1, Minor: 1}
+
+	tests := []struct {
+		name      string
+		cfg       *config.Config
+		published map[string]bool
+		min       *version.Version
+		want      []string
+	}{
+		{
+			name:      "upstream scheme",
+			cfg:       &config.Config{},
+			published: map[string]bool{"1.2.0": true},
+			want:      []string{"v1.0.0 1.0.0", "v1.0.1 1.0.1", "v1.10.0 1.10.0"},
+		},
+		{
+			name:      "revision scheme",
+			cfg:       &config.Config{VersionScheme: version.SchemeRevision},
+			published: map[string]bool{"1.0.1000": true, "1.0.1": true},
+			want:      []string{"v1.0.1 1.0.2000", "v1.2.0 1.2.1000", "v1.10.0 1.10.1000"},
+		},
+		{
+			name: "since",
+			cfg:  &config.Config{},
+			min:  &since,
+			want: []string{"v1.2.0 1.2.0", "v1.10.0 1.10.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releases, err := backfill.Missing(tt.cfg, tags, tt.published, tt.min)
+			if err != nil {
+				t.Fatalf("Missing failed: %v", err)
+			}
+			var got []string
+			for _, r := range releases {
+				got = append(got, r.Tag+" "+r.Version)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Missing() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Missing() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	fork, err := manifest.ParseObject([]byte(`{"name": "ext-fork", "displayName": "Ext Fork", "version": "1.0.0", "description": "Fork", "bugs": {"url": "https://example.com/issues"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := backfill.Release{Tag: "v1.2.0", Version: "1.2.1000"}
+
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		want map[string]string
+	}{
+		{
+			name: "default fields",
+			cfg:  &config.Config{Publisher: "pub"},
+			want: map[string]string{
+				"name":        `"ext-fork"`,
+				"displayName": `"Ext Fork"`,
+				"bugs":        `{"url": "https://example.com/issues"}`,
+				"publisher":   `"pub"`,
+				"version":     `"1.2.1000"`,
+			},
+		},
+		{
+			name: "policy fields",
+			cfg: &config.Config{Publisher: "pub", ConflictPolicies: []config.ConflictPolicy{
+				{Path: "package.json", Policy: config.PolicyJSONFieldMerge, Fields: []string{"description"}},
+			}},
+			want: map[string]string{
+				"name":        `"ext-fork"`,
+				"description": `"Fork"`,
+				"publisher":   `"pub"`,
+				"version":     `"1.2.1000"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := backfill.Overrides(tt.cfg, fork, r)
+			if len(got) != len(tt.want) {
+				t.Errorf("Overrides() has fields %v, want %v", got, tt.want)
+			}
+			for field, want := range tt.want {
+				if string(got[field]) != want {
+					t.Errorf("Overrides()[%q] = %s, want %s", field, got[field], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package bump implements the bump command, which computes the next fork version
+// of the extension and the tag it will be released under.
+//
+// Usage:
+//
+//	ovsx-setup bump [--base] [--write] [--tag] [--push]
+//
+// The version and tag are printed as "key=value" lines so the output can be
+// appended to $GITHUB_OUTPUT. The version is never committed; --write only patches
+// the working copy of package.json so that it ends up in the packaged manifest.
+package bump
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// Result describes a computed fork version.
+type Result struct {
+	Upstream string
+	Version  string
+	Tag      string
+	Revision int
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("bump", flag.ContinueOnError)
+	base := fs.Bool("base", false, "Print the base fork version of the current upstream version instead of the next revision")
+	write := fs.Bool("write", false, "Write the version into the extension's package.json")
+	createTag := fs.Bool("tag", false, "Create an annotated tag for the version")
+	push := fs.Bool("push", false, "Push the created tag to origin (implies --tag)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+
+	tags, err := git.Tags()
+	if err != nil {
+		return err
+	}
+
+	res, err := Next(cfg, m, tags, *base)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version=%s\n", res.Version)
+	fmt.Printf("tag=%s\n", res.Tag)
+
+	if *write {
+		if err := manifest.Update(cfg.ExtensionDir(), map[string]string{"version": res.Version}); err != nil {
+			return fmt.Errorf("failed to write version: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote version %s to %s\n", res.Version, manifest.FileName)
+	}
+
+	if *createTag || *push {
+		if _, err := git.Output("tag", "-a", res.Tag, "-m", "Release "+res.Tag); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created tag %s\n", res.Tag)
+	}
+
+	if *push {
+		if _, err := git.Output("push", "origin", res.Tag); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Pushed tag %s\n", res.Tag)
+	}
+
+	return nil
+}
+
+// Next computes the fork version to release for the manifest's upstream version.
+// With base set it returns revision 0 whether or not it is already tagged;
+// otherwise it returns the first revision that has no tag yet.
+func Next(cfg *config.Config, m *manifest.Manifest, tags map[string]bool, base bool) (*Result, error) {
+	if err := tag.ValidatePath(cfg.TagTemplate, cfg.ExtensionPath); err != nil {
+		return nil, err
+	}
+	if cfg.VersionScheme != version.SchemeRevision {
+		tag := cfg.Tag(m.Name, m.Version)
+		if !base && tags[tag] {
+			return nil, fmt.Errorf("tag %s already exists; the %q version scheme cannot release fork-only changes (use --version-scheme %s)", tag, version.SchemeUpstream, version.SchemeRevision)
+		}
+		return &Result{Upstream: m.Version, Version: m.Version, Tag: tag}, nil
+	}
+
+	upstream, err := version.Parse(m.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	for rev := 0; ; rev++ {
+		v, err := version.Fork(upstream, rev, cfg.Digits())
+		if err != nil {
+			return nil, err
+		}
+		tag := cfg.Tag(m.Name, v.String())
+		if base || !tags[tag] {
+			return &Result{Upstream: m.Version, Version: v.String(), Tag: tag, Revision: rev}, nil
+		}
+	}
+}
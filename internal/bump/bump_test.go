@@ -0,0 +1,60 @@
+package bump_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/bump"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+)
+
+func TestNext(t *testing.T) {
+	m := &manifest.Manifest{Name: "ext", Version: "1.2.3"}
+	revision := &config.Config{VersionScheme: "revision"}
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		tags    []string
+		base    bool
+		want    string
+		wantTag string
+		wantErr string
+	}{
+		{name: "upstream untagged", cfg: &config.Config{}, want: "1.2.3", wantTag: "v1.2.3"},
+		{name: "upstream base tagged", cfg: &config.Config{}, tags: []string{"v1.2.3"}, base: true, want: "1.2.3", wantTag: "v1.2.3"},
+		{name: "upstream tagged", cfg: &config.Config{}, tags: []string{"v1.2.3"}, wantErr: "cannot release fork-only changes"},
+		{name: "revision untagged", cfg: revision, want: "1.2.4000", wantTag: "v1.2.4000"},
+		{name: "revision base", cfg: revision, tags: []string{"v1.2.4000", "v1.2.4001"}, base: true, want: "1.2.4000", wantTag: "v1.2.4000"},
+		{name: "revision next", cfg: revision, tags: []string{"v1.2.4000", "v1.2.4001"}, want: "1.2.4002", wantTag: "v1.2.4002"},
+		{name: "revision ignores other versions", cfg: revision, tags: []string{"v1.2.3000", "v1.2.3001"}, want: "1.2.4000", wantTag: "v1.2.4000"},
+		{name: "revision digits", cfg: &config.Config{VersionScheme: "revision", RevisionDigits: 1}, tags: []string{"v1.2.40"}, want: "1.2.41", wantTag: "v1.2.41"},
+		{name: "revision template", cfg: &config.Config{VersionScheme: "revision", TagTemplate: "{name}@{version}"}, tags: []string{"ext@1.2.4000"}, want: "1.2.4001", wantTag: "ext@1.2.4001"},
+		{name: "path template", cfg: &config.Config{TagTemplate: "{path}/v{version}", ExtensionPath: "./packages/ext"}, want: "1.2.3", wantTag: "packages/ext/v1.2.3"},
+		{name: "path template at the root", cfg: &config.Config{TagTemplate: "{path}/v{version}"}, wantErr: "repository root"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := map[string]bool{}
+			for _, tag := range tt.tags {
+				tags[tag] = true
+			}
+
+			res, err := bump.Next(tt.cfg, m, tags, tt.base)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Version != tt.want || res.Tag != tt.wantTag {
+				t.Errorf("got %s (%s), want %s (%s)", res.Version, res.Tag, tt.want, tt.wantTag)
+			}
+		})
+	}
+}
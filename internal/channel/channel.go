@@ -0,0 +1,55 @@
+// Package channel implements the channel command, which tells whether a release is
+// published as a pre-release.
+//
+// Usage:
+//
+//	ovsx-setup channel --branch <name> [--version <version>]
+//
+// The channel is printed as a "channel=stable" or "channel=pre-release" line so the
+// output can be appended to $GITHUB_OUTPUT. Releases from a configured pre-release
+// branch, or whose version matches the pre-release rule, are pre-releases.
+package channel
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+)
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("channel", flag.ContinueOnError)
+	branch := fs.String("branch", "", "The branch the release is made from")
+	ver := fs.String("version", "", "The released version (default: the version in the extension's package.json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *branch == "" {
+		return fmt.Errorf("--branch is required")
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if *ver == "" {
+		m, err := manifest.Read(cfg.ExtensionDir())
+		if err != nil {
+			return err
+		}
+		*ver = m.Version
+	}
+
+	channel, err := cfg.Channel(*branch, *ver)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("channel=%s\n", channel)
+	return nil
+}
@@ -0,0 +1,253 @@
+// Package checkversion implements the check-version command, which tells whether merging a
+// pull request releases a new version.
+//
+// Usage:
+//
+//	ovsx-setup check-version --branch <name> [--pr <number>] [--markdown <file>]
+//
+// It computes the version and tag the release workflow publishes on merge (with the
+// revision scheme the base fork version, as 'bump --base' does) and its channel. It then
+// checks whether the tag already exists and, for each registry the release workflow
+// publishes to, whether the version is already published there and whether it is lower
+// than the latest published version.
+//
+// The version, tag, channel and whether merging releases are printed as "key=value" lines
+// so the output can be appended to $GITHUB_OUTPUT, and each problem as a workflow warning.
+// With --pr the summary is posted as a comment on the pull request; later runs update the
+// same comment. Failing to comment, e.g. with the read-only token of a pull request from
+// another fork, is only a warning.
+package checkversion
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/bump"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gh"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// commentMarker identifies the pull request comment the command keeps up to date.
+const commentMarker = "<!-- ovsx-fork-tools:check-version -->"
+
+// Result describes what merging releases.
+type Result struct {
+	Version   string
+	Tag       string
+	Channel   string
+	TagExists bool
+	// Registries are the lookups of the version in each registry the workflow publishes to.
+	Registries []Registry
+}
+
+// Registry is the state of the version in a registry.
+type Registry struct {
+	URL       string
+	Published bool
+	// Latest is the latest published version, empty if the extension was never published.
+	Latest string
+	// Error explains why the registry could not be checked.
+	Error string
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("check-version", flag.ContinueOnError)
+	branch := fs.String("branch", "", "The branch the pull request merges into")
+	pr := fs.Int("pr", 0, "Comment the summary on this pull request")
+	markdown := fs.String("markdown", "", "Also write the summary as Markdown to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *branch == "" {
+		return fmt.Errorf("--branch is required")
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+	tags, err := git.Tags()
+	if err != nil {
+		return err
+	}
+
+	var clients []*openvsx.Client
+	for _, r := range cfg.PublishTargets() {
+		clients = append(clients, openvsx.New(r.URL))
+	}
+	res, err := Check(cfg, m, tags, *branch, clients)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version=%s\n", res.Version)
+	fmt.Printf("tag=%s\n", res.Tag)
+	fmt.Printf("channel=%s\n", res.Channel)
+	fmt.Printf("release=%t\n", res.WillRelease())
+	for _, w := range res.Warnings() {
+		fmt.Fprintf(os.Stderr, "::warning::%s\n", w)
+	}
+
+	if *markdown != "" {
+		f, err := os.Create(*markdown)
+		if err != nil {
+			return err
+		}
+		res.WriteMarkdown(f)
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if *pr != 0 {
+		var body bytes.Buffer
+		res.WriteMarkdown(&body)
+		// Pull requests from other forks get a read-only token, so a failed comment is not fatal
+		if err := comment(*pr, body.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "::warning::Could not comment on pull request #%d: %v\n", *pr, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Updated the version check comment on #%d\n", *pr)
+		}
+	}
+	return nil
+}
+
+// comment creates or updates the version check comment on pull request pr.
+func comment(pr int, body string) error {
+	repo, err := gh.Repo()
+	if err != nil {
+		return err
+	}
+	return gh.Comment(repo, pr, commentMarker, body)
+}
+
+// Check computes the release of the manifest's version from branch and looks it up in the registries.
+func Check(cfg *config.Config, m *manifest.Manifest, tags map[string]bool, branch string, registries []*openvsx.Client) (*Result, error) {
+	next, err := bump.Next(cfg, m, tags, true)
+	if err != nil {
+		return nil, err
+	}
+	channel, err := cfg.Channel(branch, next.Version)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Version: next.Version, Tag: next.Tag, Channel: channel, TagExists: tags[next.Tag]}
+
+	for _, c := range registries {
+		reg := Registry{URL: c.URL}
+		if cfg.Publisher == "" {
+			reg.Error = "no publisher configured"
+		} else if err := lookup(c, cfg.Publisher, m.Name, res.Version, &reg); err != nil {
+			reg.Error = err.Error()
+		}
+		res.Registries = append(res.Registries, reg)
+	}
+	return res, nil
+}
+
+// lookup fills in whether ver is published and the latest published version.
+func lookup(c *openvsx.Client, namespace, name, ver string, reg *Registry) error {
+	latest, err := c.Extension(namespace, name, "")
+	if errors.Is(err, openvsx.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reg.Latest = latest.Version
+	if latest.Version == ver {
+		reg.Published = true
+		return nil
+	}
+	if _, ok := latest.AllVersions[ver]; ok {
+		reg.Published = true
+		return nil
+	}
+	_, err = c.Extension(namespace, name, ver)
+	if errors.Is(err, openvsx.ErrNotFound) {
+		return nil
+	}
+	reg.Published = err == nil
+	return err
+}
+
+// WillRelease reports whether merging creates the tag, which triggers the release.
+func (r *Result) WillRelease() bool {
+	return !r.TagExists
+}
+
+// Warnings lists the problems with releasing the version.
+func (r *Result) Warnings() []string {
+	var warnings []string
+	if r.TagExists {
+		warnings = append(warnings, fmt.Sprintf("Tag %s already exists: merging will not release unless the version is bumped.", r.Tag))
+	}
+	v, verr := version.Parse(r.Version)
+	for _, reg := range r.Registries {
+		switch {
+		case reg.Error != "":
+			warnings = append(warnings, fmt.Sprintf("Could not check %s: %s", reg.URL, reg.Error))
+		case reg.Published:
+			warnings = append(warnings, fmt.Sprintf("Version %s is already published to %s: publishing it there again will fail.", r.Version, reg.URL))
+		case reg.Latest != "" && verr == nil:
+			if latest, err := version.Parse(reg.Latest); err == nil && v.Compare(latest) < 0 {
+				warnings = append(warnings, fmt.Sprintf("Version %s is lower than %s, the latest version on %s: users of %s will not be updated to it.", r.Version, reg.Latest, reg.URL, reg.Latest))
+			}
+		}
+	}
+	return warnings
+}
+
+// WriteMarkdown writes the summary posted on the pull request.
+func (r *Result) WriteMarkdown(w io.Writer) {
+	fmt.Fprintln(w, "## Version Check")
+	fmt.Fprintln(w)
+	if r.WillRelease() {
+		fmt.Fprintf(w, "Merging will release **%s** to the **%s** channel (tag `%s`).\n", r.Version, r.Channel, r.Tag)
+	} else {
+		fmt.Fprintf(w, "Merging will **not** release: tag `%s` already exists.\n", r.Tag)
+	}
+
+	if len(r.Registries) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Registry | Version `"+r.Version+"` | Latest |")
+		fmt.Fprintln(w, "| --- | --- | --- |")
+		for _, reg := range r.Registries {
+			status, latest := "not published", reg.Latest
+			switch {
+			case reg.Error != "":
+				status = "unknown"
+			case reg.Published:
+				status = "published"
+			}
+			if latest == "" {
+				latest = "—"
+			}
+			fmt.Fprintf(w, "| %s | %s | %s |\n", reg.URL, status, latest)
+		}
+	}
+
+	if warnings := r.Warnings(); len(warnings) > 0 {
+		fmt.Fprintln(w)
+		for _, warning := range warnings {
+			fmt.Fprintf(w, "- ⚠️ %s\n", warning)
+		}
+	}
+}
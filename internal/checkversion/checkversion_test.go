@@ -0,0 +1,146 @@
+package checkversion_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/checkversion"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+)
+
+// registry serves an extension whose published versions are listed latest first.
+func registry(t *testing.T, versions ...string) *openvsx.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(versions) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var all []string
+		for _, v := range versions {
+			all = append(all, fmt.Sprintf("%q: \"\"", v))
+		}
+		switch r.URL.Path {
+		case "/api/pub/ext":
+			fmt.Fprintf(w, `{"namespace": "pub", "name": "ext", "version": %q, "allVersions": {%s}}`, versions[0], strings.Join(all, ", "))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return openvsx.New(srv.URL)
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		version     string
+		tags        map[string]bool
+		published   []string
+		wantVersion string
+		wantRelease bool
+		warnings    int
+		want        []string
+	}{
+		{
+			name:        "new version",
+			version:     "1.2.0",
+			published:   []string{"1.1.0"},
+			wantVersion: "1.2.0",
+			wantRelease: true,
+			want:        []string{"Merging will release **1.2.0** to the **stable** channel (tag `v1.2.0`).", "| not published | 1.1.0 |"},
+		},
+		{
+			name:        "never published",
+			version:     "1.0.0",
+			wantVersion: "1.0.0",
+			wantRelease: true,
+			want:        []string{"| not published | — |"},
+		},
+		{
+			name:        "tagged",
+			version:     "1.1.0",
+			tags:        map[string]bool{"v1.1.0": true},
+			published:   []string{"1.1.0"},
+			wantVersion: "1.1.0",
+			warnings:    2,
+			want:        []string{"Merging will **not** release: tag `v1.1.0` already exists.", "| published | 1.1.0 |", "Tag v1.1.0 already exists"},
+		},
+		{
+			name:        "published without a tag",
+			version:     "1.1.0",
+			published:   []string{"1.2.0", "1.1.0"},
+			wantVersion: "1.1.0",
+			wantRelease: true,
+			warnings:    1,
+			want:        []string{"| published | 1.2.0 |", "Version 1.1.0 is already published to"},
+		},
+		{
+			name:        "lower than latest",
+			version:     "1.0.5",
+			published:   []string{"1.1.0"},
+			wantVersion: "1.0.5",
+			wantRelease: true,
+			warnings:    1,
+			want:        []string{"Version 1.0.5 is lower than 1.1.0, the latest version on"},
+		},
+		{
+			name:        "revision pre-release",
+			cfg:         config.Config{VersionScheme: "revision", PreReleaseRule: config.PreReleaseOddMinor},
+			version:     "1.3.2",
+			published:   []string{"1.2.0"},
+			wantVersion: "1.3.3000",
+			wantRelease: true,
+			want:        []string{"Merging will release **1.3.3000** to the **pre-release** channel (tag `v1.3.3000`)."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Publisher = "pub"
+			m := &manifest.Manifest{Name: "ext", Version: tt.version}
+			res, err := checkversion.Check(&cfg, m, tt.tags, "main", []*openvsx.Client{registry(t, tt.published...)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Version != tt.wantVersion || res.WillRelease() != tt.wantRelease {
+				t.Errorf("Check() = %s, release %t, want %s, release %t", res.Version, res.WillRelease(), tt.wantVersion, tt.wantRelease)
+			}
+
+			var out bytes.Buffer
+			res.WriteMarkdown(&out)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("summary does not contain %q:\n%s", want, out.String())
+				}
+			}
+			if got := len(res.Warnings()); got != tt.warnings {
+				t.Errorf("expected %d warnings, got %d:\n%s", tt.warnings, got, out.String())
+			}
+		})
+	}
+}
+
+func TestCheckRegistryError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Publisher: "pub"}
+	res, err := checkversion.Check(cfg, &manifest.Manifest{Name: "ext", Version: "1.0.0"}, nil, "main", []*openvsx.Client{openvsx.New(srv.URL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	res.WriteMarkdown(&out)
+	if !strings.Contains(out.String(), "| unknown |") || !strings.Contains(out.String(), "Could not check "+srv.URL) {
+		t.Errorf("expected the registry to be reported as unknown:\n%s", out.String())
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// nodeVersion matches the versions actions/setup-node accepts that are safe to render
+// into a workflow: "20", "20.x", "20.11.1", "lts/*", "lts/iron", "node" and "latest".
+var nodeVersion = regexp.MustCompile(`^(\d+(\.(\d+|x)){0,2}|lts/(\*|[a-z-]+)|node|latest)$`)
+
+// reservedPackageArgs are the vsce package arguments the release workflow sets itself.
+var reservedPackageArgs = []string{"-t", "--target", "--pre-release", "-o", "--out"}
+
+// PackageArguments returns the extra arguments passed to 'vsce package'.
+func (c *Config) PackageArguments() []string {
+	return strings.Fields(c.PackageArgs)
+}
+
+// validateBuild checks the build options.
+func (c *Config) validateBuild() error {
+	if c.NodeVersion != "" && !nodeVersion.MatchString(c.NodeVersion) {
+		return fmt.Errorf("invalid Node.js version %q: expected a version like 20, 20.x or lts/*", c.NodeVersion)
+	}
+	if c.BuildCommand != "" && c.BuildScope != "" {
+		return fmt.Errorf("the build scope cannot be combined with a build command, which replaces the default build")
+	}
+	for _, arg := range c.PackageArguments() {
+		name, _, _ := strings.Cut(arg, "=")
+		if slices.Contains(reservedPackageArgs, name) {
+			return fmt.Errorf("package argument %s is set by the release workflow and cannot be configured", name)
+		}
+	}
+	return nil
+}
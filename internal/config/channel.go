@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// Release channels.
+const (
+	ChannelStable     = "stable"
+	ChannelPreRelease = "pre-release"
+)
+
+// PreReleaseOddMinor publishes versions with an odd minor number as pre-releases,
+// following the VS Code convention (1.3.x pre-release, 1.4.x stable).
+const PreReleaseOddMinor = "odd-minor"
+
+// defaultReleaseBranches are the branches the workflow templates release from.
+var defaultReleaseBranches = []string{"main", "master"}
+
+// Channel returns the channel a release of ver from branch is published to. It is the
+// pre-release channel when branch matches one of the PreReleaseBranches (path.Match
+// patterns) or ver matches the PreReleaseRule.
+func (c *Config) Channel(branch, ver string) (string, error) {
+	for _, p := range c.PreReleaseBranches {
+		if ok, _ := path.Match(p, branch); ok {
+			return ChannelPreRelease, nil
+		}
+	}
+	if c.PreReleaseRule == PreReleaseOddMinor {
+		v, err := version.Parse(ver)
+		if err != nil {
+			return "", err
+		}
+		if v.Minor%2 == 1 {
+			return ChannelPreRelease, nil
+		}
+	}
+	return ChannelStable, nil
+}
+
+// validatePreRelease checks the pre-release rule and branches.
+func (c *Config) validatePreRelease() error {
+	switch c.PreReleaseRule {
+	case "", PreReleaseOddMinor:
+	default:
+		return fmt.Errorf("unknown pre-release rule %q (expected %q)", c.PreReleaseRule, PreReleaseOddMinor)
+	}
+	for _, b := range c.PreReleaseBranches {
+		if err := validateBranch(b, true); err != nil {
+			return fmt.Errorf("invalid pre-release branch: %w", err)
+		}
+	}
+	return nil
+}
+
+// withPreReleaseBranches adds the pre-release branches missing from the release branches,
+// which default to the workflow templates' branches.
+func (c *Config) withPreReleaseBranches(branches []string) []string {
+	if len(c.PreReleaseBranches) == 0 {
+		return branches
+	}
+	if len(branches) == 0 {
+		branches = defaultReleaseBranches
+	}
+	branches = slices.Clone(branches)
+	for _, b := range c.PreReleaseBranches {
+		if !slices.Contains(branches, b) {
+			branches = append(branches, b)
+		}
+	}
+	return branches
+}
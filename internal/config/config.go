@@ -0,0 +1,426 @@
+// Package config reads and writes the fork configuration recorded by the setup tool.
+// The configuration lives in the fork repository so that re-running setup, and the
+// other commands of the tool, see the same options that were used to render the workflows.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/cron"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// Path is the location of the configuration file relative to the repository root.
+var Path = filepath.Join(".ovsx-fork", "config.json")
+
+// Repository paths used by the patches sync strategy, in git's slash-separated form.
+const (
+	// PatchDir holds the fork's patch series, applied in file name order.
+	PatchDir = ".ovsx-fork/patches"
+	// UpstreamRevisionPath records the upstream commit the fork was last rebuilt from.
+	UpstreamRevisionPath = ".ovsx-fork/upstream"
+)
+
+// Config holds the options chosen at setup time.
+type Config struct {
+	Publisher     string `json:"publisher,omitempty"`
+	ExtensionPath string `json:"extensionPath,omitempty"`
+	TagTemplate   string `json:"tagTemplate,omitempty"`
+
+	VersionScheme  string `json:"versionScheme,omitempty"`
+	RevisionDigits int    `json:"revisionDigits,omitempty"`
+
+	SyncMode           string `json:"syncMode,omitempty"`
+	UpstreamTagPattern string `json:"upstreamTagPattern,omitempty"`
+	// SyncStrategy is how upstream changes are brought into the fork; see SyncStrategyMerge
+	// and SyncStrategyPatches.
+	SyncStrategy string `json:"syncStrategy,omitempty"`
+
+	UpstreamURL    string `json:"upstreamUrl,omitempty"`
+	UpstreamBranch string `json:"upstreamBranch,omitempty"`
+
+	ConflictPolicies []ConflictPolicy `json:"conflictPolicies,omitempty"`
+
+	// SyncPaths limit the sync to upstream changes in these directories (or files), plus the
+	// SharedFiles at the repository root. Upstream changes elsewhere are left out of the merge.
+	SyncPaths   []string `json:"syncPaths,omitempty"`
+	SharedFiles []string `json:"sharedFiles,omitempty"`
+	// BuildScope selects what the release workflow builds; see BuildScopeAll and BuildScopeExtension.
+	BuildScope string `json:"buildScope,omitempty"`
+	// PreBuildCommand runs from the repository root after the dependencies are installed.
+	// BuildCommand replaces the default build, and PackageArgs are extra 'vsce package' arguments.
+	PreBuildCommand string `json:"preBuildCommand,omitempty"`
+	BuildCommand    string `json:"buildCommand,omitempty"`
+	PackageArgs     string `json:"packageArgs,omitempty"`
+	// NodeVersion is the Node.js version the workflows set up; empty means the latest LTS.
+	NodeVersion string `json:"nodeVersion,omitempty"`
+
+	// SyncSchedule is the cron expression the sync workflow runs on, in UTC.
+	SyncSchedule string `json:"syncSchedule,omitempty"`
+	// SyncBranch is the branch upstream is merged into and proposed from.
+	SyncBranch string `json:"syncBranch,omitempty"`
+	// BaseBranch is the fork branch sync pull requests target; empty means the default branch.
+	BaseBranch string `json:"baseBranch,omitempty"`
+	// ReleaseBranches are the branches (or patterns) that release and check versions.
+	// Without them the base branch is used, or main and master when that is not set either.
+	ReleaseBranches []string `json:"releaseBranches,omitempty"`
+
+	// AutoMerge enables auto-merge on sync pull requests; nil leaves it to the AUTO_MERGE variable.
+	AutoMerge   *bool  `json:"autoMerge,omitempty"`
+	MergeMethod string `json:"mergeMethod,omitempty"`
+
+	// TokenSecret names the secret holding a personal access token for the sync workflow.
+	// AppID is the ID of a GitHub App whose private key is stored in the SYNC_APP_PRIVATE_KEY secret.
+	// Either lets a merged sync pull request trigger the release workflow, which pushes made
+	// with the default GITHUB_TOKEN cannot.
+	TokenSecret string `json:"tokenSecret,omitempty"`
+	AppID       string `json:"appId,omitempty"`
+	// DispatchRelease makes the sync workflow dispatch the release workflow itself once an
+	// auto-merged sync pull request is merged; nil leaves it to the DISPATCH_RELEASE variable.
+	DispatchRelease *bool `json:"dispatchRelease,omitempty"`
+
+	// Registries are the OpenVSX instances the release workflow publishes to; see PublishTargets.
+	Registries []Registry `json:"registries,omitempty"`
+	// Marketplace also publishes releases to the VS Marketplace with the MarketplaceTokenSecret;
+	// nil leaves it to the MARKETPLACE variable.
+	Marketplace *bool `json:"marketplace,omitempty"`
+	// Targets are the platforms packaged separately, in addition to the universal package.
+	Targets []string `json:"targets,omitempty"`
+
+	// PreReleaseBranches are branches (or patterns) whose releases are published as pre-releases.
+	// They release on push like the ReleaseBranches.
+	PreReleaseBranches []string `json:"preReleaseBranches,omitempty"`
+	// PreReleaseRule publishes matching versions as pre-releases; see PreReleaseOddMinor.
+	PreReleaseRule string `json:"preReleaseRule,omitempty"`
+
+	// SBOMFormat makes the release workflow generate an SBOM in this format and a provenance
+	// statement next to each .vsix; see SBOMCycloneDX and SBOMSPDX.
+	SBOMFormat string `json:"sbomFormat,omitempty"`
+}
+
+const (
+	// SyncModeBranch merges upstream's default branch.
+	SyncModeBranch = "branch"
+	// SyncModeTag merges upstream's latest release tag matching UpstreamTagPattern.
+	SyncModeTag = "tag"
+
+	// SyncStrategyMerge merges upstream into the fork.
+	SyncStrategyMerge = "merge"
+	// SyncStrategyPatches rebuilds the fork from upstream and the patch series in PatchDir.
+	SyncStrategyPatches = "patches"
+
+	// DefaultUpstreamTagPattern matches upstream release tags when no pattern is configured.
+	DefaultUpstreamTagPattern = "v*"
+
+	// Merge methods for sync pull requests, as accepted by 'gh pr merge'.
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+
+	// DefaultSyncSchedule runs the sync workflow daily at 3 AM UTC.
+	DefaultSyncSchedule = "0 3 * * *"
+	// DefaultSyncBranch is the branch upstream is merged into.
+	DefaultSyncBranch = "upstream-sync"
+
+	// DefaultTokenSecret is the secret the sync workflow reads a personal access token from.
+	DefaultTokenSecret = "SYNC_TOKEN"
+	// AppKeySecret is the secret holding the private key of the sync workflow's GitHub App.
+	AppKeySecret = "SYNC_APP_PRIVATE_KEY"
+
+	// SBOM formats, as generated from the extension's lockfile.
+	SBOMCycloneDX = "cyclonedx"
+	SBOMSPDX      = "spdx"
+)
+
+var (
+	secretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	appID      = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Load reads the configuration from the repository rooted at dir.
+// A missing file is not an error; an empty configuration is returned instead.
+func Load(dir string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Join(dir, Path))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", Path, err)
+	}
+	return &cfg, nil
+}
+
+// Save writes the configuration to the repository rooted at dir.
+func (c *Config) Save(dir string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	dest := filepath.Join(dir, Path)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, 0644)
+}
+
+// Validate checks the configured values.
+func (c *Config) Validate() error {
+	if c.TagTemplate != "" {
+		if err := tag.Validate(c.TagTemplate, c.ExtensionPath); err != nil {
+			return err
+		}
+	}
+	if err := version.ValidateScheme(c.VersionScheme); err != nil {
+		return err
+	}
+	if c.RevisionDigits < 0 || c.RevisionDigits > 6 {
+		return fmt.Errorf("revision digits must be between 1 and 6, got %d", c.RevisionDigits)
+	}
+	switch c.SyncMode {
+	case "", SyncModeBranch, SyncModeTag:
+	default:
+		return fmt.Errorf("unknown sync mode %q (expected %q or %q)", c.SyncMode, SyncModeBranch, SyncModeTag)
+	}
+	switch c.SyncStrategy {
+	case "", SyncStrategyMerge:
+	case SyncStrategyPatches:
+		if c.Scoped() {
+			return fmt.Errorf("sync paths cannot be combined with the %q sync strategy", SyncStrategyPatches)
+		}
+	default:
+		return fmt.Errorf("unknown sync strategy %q (expected %q or %q)", c.SyncStrategy, SyncStrategyMerge, SyncStrategyPatches)
+	}
+	if _, err := path.Match(c.UpstreamTagPattern, ""); err != nil {
+		return fmt.Errorf("invalid upstream tag pattern %q: %w", c.UpstreamTagPattern, err)
+	}
+	if c.UpstreamURL != "" {
+		if err := validateRemoteURL(c.UpstreamURL); err != nil {
+			return err
+		}
+	}
+	for _, p := range c.ConflictPolicies {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	switch c.MergeMethod {
+	case "", MergeMethodMerge, MergeMethodSquash, MergeMethodRebase:
+	default:
+		return fmt.Errorf("unknown merge method %q (expected %q, %q or %q)", c.MergeMethod, MergeMethodMerge, MergeMethodSquash, MergeMethodRebase)
+	}
+	for _, p := range c.SyncPaths {
+		if err := validateSyncPath(p); err != nil {
+			return err
+		}
+	}
+	for _, f := range c.SharedFiles {
+		if strings.Contains(f, "/") || f == "." || f == ".." {
+			return fmt.Errorf("invalid shared file %q: expected a file name at the repository root", f)
+		}
+	}
+	switch c.BuildScope {
+	case "", BuildScopeAll, BuildScopeExtension:
+	default:
+		return fmt.Errorf("unknown build scope %q (expected %q or %q)", c.BuildScope, BuildScopeAll, BuildScopeExtension)
+	}
+	if err := c.validateBuild(); err != nil {
+		return err
+	}
+	if c.SyncSchedule != "" {
+		if err := cron.Validate(c.SyncSchedule); err != nil {
+			return err
+		}
+	}
+	if c.SyncBranch != "" {
+		if err := validateBranch(c.SyncBranch, false); err != nil {
+			return fmt.Errorf("invalid sync branch: %w", err)
+		}
+	}
+	if c.BaseBranch != "" {
+		if err := validateBranch(c.BaseBranch, false); err != nil {
+			return fmt.Errorf("invalid base branch: %w", err)
+		}
+	}
+	if c.BaseBranch != "" && c.SyncBranchName() == c.BaseBranch {
+		return fmt.Errorf("the sync branch and the base branch must differ, both are %q", c.BaseBranch)
+	}
+	for _, b := range c.ReleaseBranches {
+		if err := validateBranch(b, true); err != nil {
+			return fmt.Errorf("invalid release branch: %w", err)
+		}
+	}
+	if c.TokenSecret != "" && !validSecretName(c.TokenSecret) {
+		return fmt.Errorf("invalid token secret name %q: use letters, digits and underscores, not starting with a digit or GITHUB_", c.TokenSecret)
+	}
+	if c.AppID != "" && !appID.MatchString(c.AppID) {
+		return fmt.Errorf("invalid GitHub App ID %q: expected a number", c.AppID)
+	}
+	// The dispatch waits for the sync pull request's auto-merge, so it never fires without one
+	if c.DispatchRelease != nil && *c.DispatchRelease && (c.AutoMerge == nil || !*c.AutoMerge) {
+		return fmt.Errorf("dispatching the release requires auto-merge on sync pull requests")
+	}
+	seen := map[string]bool{}
+	for _, r := range c.Registries {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if seen[r.URL] {
+			return fmt.Errorf("registry %s is configured twice", r.URL)
+		}
+		seen[r.URL] = true
+	}
+	if err := validateTargets(c.Targets); err != nil {
+		return err
+	}
+	switch c.SBOMFormat {
+	case "", SBOMCycloneDX, SBOMSPDX:
+	default:
+		return fmt.Errorf("unknown SBOM format %q (expected %q or %q)", c.SBOMFormat, SBOMCycloneDX, SBOMSPDX)
+	}
+	return c.validatePreRelease()
+}
+
+// validSecretName reports whether name can be used as an Actions secret name.
+func validSecretName(name string) bool {
+	return secretName.MatchString(name) && !strings.HasPrefix(strings.ToUpper(name), "GITHUB_")
+}
+
+// ApplyEnv overrides configured values with the environment variables the workflows set,
+// so that forks configured through repository variables rather than the configuration
+// file behave the same when the workflows run the tool.
+func (c *Config) ApplyEnv() {
+	for name, field := range map[string]*string{
+		"EXTENSION_PATH":       &c.ExtensionPath,
+		"PUBLISHER_NAME":       &c.Publisher,
+		"TAG_TEMPLATE":         &c.TagTemplate,
+		"VERSION_SCHEME":       &c.VersionScheme,
+		"SYNC_MODE":            &c.SyncMode,
+		"SYNC_STRATEGY":        &c.SyncStrategy,
+		"UPSTREAM_TAG_PATTERN": &c.UpstreamTagPattern,
+		"UPSTREAM_URL":         &c.UpstreamURL,
+		"UPSTREAM_BRANCH":      &c.UpstreamBranch,
+		"SYNC_BRANCH":          &c.SyncBranch,
+		"PRE_RELEASE_RULE":     &c.PreReleaseRule,
+		"SBOM_FORMAT":          &c.SBOMFormat,
+		"BASE_BRANCH":          &c.BaseBranch,
+	} {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
+	}
+}
+
+// scpLikeURL matches the "user@host:path" form accepted by git for SSH remotes.
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/].*$`)
+
+// validateRemoteURL checks that u is a git remote URL the sync workflow can fetch from.
+func validateRemoteURL(u string) error {
+	if scpLikeURL.MatchString(u) {
+		return nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("invalid upstream URL %q: %w", u, err)
+	}
+	switch parsed.Scheme {
+	case "https", "http", "ssh", "git", "file":
+	default:
+		return fmt.Errorf("invalid upstream URL %q: expected an https, ssh, git or file URL", u)
+	}
+	if (parsed.Host == "" && parsed.Scheme != "file") || strings.Trim(parsed.Path, "/") == "" {
+		return fmt.Errorf("invalid upstream URL %q: missing host or repository path", u)
+	}
+	return nil
+}
+
+// validateBranch applies the rules of git check-ref-format to a branch name. With pattern
+// set, the "*" wildcard of GitHub's branch filters is allowed as well.
+func validateBranch(name string, pattern bool) error {
+	invalid := " ~^:?*[\\"
+	if pattern {
+		invalid = " ~^:?[\\"
+	}
+	switch {
+	case name == "":
+		return fmt.Errorf("branch name is empty")
+	case strings.ContainsAny(name, invalid):
+		return fmt.Errorf("%q contains a character not allowed in branch names", name)
+	case strings.Contains(name, "..") || strings.Contains(name, "@{") || strings.Contains(name, "//"):
+		return fmt.Errorf("%q contains a sequence not allowed in branch names", name)
+	case strings.HasPrefix(name, "/") || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "."):
+		return fmt.Errorf("%q must not start with %q", name, name[:1])
+	case strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".") || strings.HasSuffix(name, ".lock"):
+		return fmt.Errorf("%q has an invalid ending", name)
+	}
+	return nil
+}
+
+// SyncBranchName returns the branch upstream is merged into.
+func (c *Config) SyncBranchName() string {
+	if c.SyncBranch == "" {
+		return DefaultSyncBranch
+	}
+	return c.SyncBranch
+}
+
+// Releases returns the configured release branches, defaulting to the base branch, plus
+// the pre-release branches. It is empty when none is configured, leaving the workflows'
+// main and master defaults.
+func (c *Config) Releases() []string {
+	if len(c.ReleaseBranches) > 0 {
+		return c.withPreReleaseBranches(c.ReleaseBranches)
+	}
+	if c.BaseBranch != "" {
+		return c.withPreReleaseBranches([]string{c.BaseBranch})
+	}
+	return c.withPreReleaseBranches(nil)
+}
+
+// ExtensionDir returns the extension path, defaulting to the repository root.
+func (c *Config) ExtensionDir() string {
+	if c.ExtensionPath == "" {
+		return "."
+	}
+	return c.ExtensionPath
+}
+
+// Tag renders the release tag for an extension version.
+func (c *Config) Tag(name, ver string) string {
+	template := c.TagTemplate
+	if template == "" {
+		template = tag.Default(c.ExtensionPath)
+	}
+	return tag.Render(template, tag.Fields{Name: name, Version: ver, Path: c.ExtensionPath})
+}
+
+// SyncTokenSecret returns the name of the secret holding the sync workflow's personal access token.
+func (c *Config) SyncTokenSecret() string {
+	if c.TokenSecret == "" {
+		return DefaultTokenSecret
+	}
+	return c.TokenSecret
+}
+
+// Digits returns the number of patch digits reserved for fork revisions.
+func (c *Config) Digits() int {
+	if c.RevisionDigits == 0 {
+		return version.DefaultRevisionDigits
+	}
+	return c.RevisionDigits
+}
@@ -0,0 +1,286 @@
+package config_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+)
+
+func TestLoadMissing(t *testing.T) {
+	cfg, err := config.Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*cfg, config.Config{}) {
+		t.Errorf("expected empty config, got %+v", cfg)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	want := config.Config{
+		Publisher:        "me",
+		ExtensionPath:    "./ext",
+		TagTemplate:      "fork/v{version}",
+		SyncMode:         config.SyncModeTag,
+		ConflictPolicies: []config.ConflictPolicy{{Path: "package.json", Policy: config.PolicyJSONFieldMerge, Fields: []string{"publisher"}}},
+	}
+	if err := want.Save(dir); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := config.Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Load = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{name: "empty", cfg: config.Config{}},
+		{name: "full", cfg: config.Config{TagTemplate: "{name}@{version}", VersionScheme: "revision", RevisionDigits: 4, SyncMode: "tag", UpstreamTagPattern: "release-*"}},
+		{name: "tag template", cfg: config.Config{TagTemplate: "v1"}, wantErr: "{version}"},
+		{name: "path tag template without a path", cfg: config.Config{TagTemplate: "{path}/v{version}"}},
+		{name: "path tag template", cfg: config.Config{TagTemplate: "{path}/v{version}", ExtensionPath: "packages/ext"}},
+		{name: "version scheme", cfg: config.Config{VersionScheme: "calver"}, wantErr: "unknown version scheme"},
+		{name: "revision digits", cfg: config.Config{RevisionDigits: 9}, wantErr: "revision digits"},
+		{name: "sync mode", cfg: config.Config{SyncMode: "rebase"}, wantErr: "unknown sync mode"},
+		{name: "tag pattern", cfg: config.Config{UpstreamTagPattern: "v["}, wantErr: "invalid upstream tag pattern"},
+		{name: "https upstream", cfg: config.Config{UpstreamURL: "https://codeberg.org/owner/ext.git"}},
+		{name: "scp upstream", cfg: config.Config{UpstreamURL: "git@gitlab.com:owner/ext.git"}},
+		{name: "upstream scheme", cfg: config.Config{UpstreamURL: "ftp://example.com/ext"}, wantErr: "invalid upstream URL"},
+		{name: "upstream path", cfg: config.Config{UpstreamURL: "https://gitlab.com/"}, wantErr: "missing host or repository path"},
+		{name: "merge method", cfg: config.Config{MergeMethod: "fast-forward"}, wantErr: "unknown merge method"},
+		{name: "patches strategy", cfg: config.Config{SyncStrategy: "patches"}},
+		{name: "sync strategy", cfg: config.Config{SyncStrategy: "rebase"}, wantErr: "unknown sync strategy"},
+		{name: "scoped patches", cfg: config.Config{SyncStrategy: "patches", SyncPaths: []string{"ext"}}, wantErr: "cannot be combined"},
+		{name: "sync paths", cfg: config.Config{SyncPaths: []string{"./packages/ext/", "shared"}, SharedFiles: []string{"pnpm-lock.yaml"}, BuildScope: "extension"}},
+		{name: "sync path outside", cfg: config.Config{SyncPaths: []string{"../other"}}, wantErr: "invalid sync path"},
+		{name: "shared file in directory", cfg: config.Config{SharedFiles: []string{"packages/package.json"}}, wantErr: "invalid shared file"},
+		{name: "build scope", cfg: config.Config{BuildScope: "changed"}, wantErr: "unknown build scope"},
+		{name: "sync schedule", cfg: config.Config{SyncSchedule: "0 */6 * * MON-FRI"}},
+		{name: "invalid sync schedule", cfg: config.Config{SyncSchedule: "daily"}, wantErr: "invalid cron expression"},
+		{name: "branches", cfg: config.Config{SyncBranch: "sync/upstream", BaseBranch: "develop", ReleaseBranches: []string{"develop", "release/*"}}},
+		{name: "sync branch", cfg: config.Config{SyncBranch: "upstream sync"}, wantErr: "invalid sync branch"},
+		{name: "base branch", cfg: config.Config{BaseBranch: "feature..x"}, wantErr: "invalid base branch"},
+		{name: "same branches", cfg: config.Config{SyncBranch: "main", BaseBranch: "main"}, wantErr: "must differ"},
+		{name: "release branch", cfg: config.Config{ReleaseBranches: []string{"release/"}}, wantErr: "invalid release branch"},
+		{name: "token secret", cfg: config.Config{TokenSecret: "RELEASE_PAT"}},
+		{name: "token secret with dash", cfg: config.Config{TokenSecret: "release-pat"}, wantErr: "invalid token secret name"},
+		{name: "reserved token secret", cfg: config.Config{TokenSecret: "GITHUB_TOKEN"}, wantErr: "invalid token secret name"},
+		{name: "app id", cfg: config.Config{AppID: "123456"}},
+		{name: "invalid app id", cfg: config.Config{AppID: "my-app"}, wantErr: "invalid GitHub App ID"},
+		{name: "dispatch release", cfg: config.Config{AutoMerge: &yes, DispatchRelease: &yes}},
+		{name: "dispatch release without auto-merge", cfg: config.Config{DispatchRelease: &yes}, wantErr: "requires auto-merge"},
+		{name: "dispatch release with auto-merge disabled", cfg: config.Config{AutoMerge: &no, DispatchRelease: &yes}, wantErr: "requires auto-merge"},
+		{name: "duplicate registry", cfg: config.Config{Registries: []config.Registry{config.DefaultRegistry, config.DefaultRegistry}}, wantErr: "configured twice"},
+		{name: "targets", cfg: config.Config{Targets: []string{"linux-x64", "darwin-arm64", "web"}}},
+		{name: "unknown target", cfg: config.Config{Targets: []string{"linux-x86"}}, wantErr: "unknown target"},
+		{name: "universal target", cfg: config.Config{Targets: []string{"universal"}}, wantErr: "always built"},
+		{name: "duplicate target", cfg: config.Config{Targets: []string{"linux-x64", "linux-x64"}}, wantErr: "listed twice"},
+		{name: "pre-release", cfg: config.Config{PreReleaseBranches: []string{"next", "preview/*"}, PreReleaseRule: "odd-minor"}},
+		{name: "unknown pre-release rule", cfg: config.Config{PreReleaseRule: "odd-patch"}, wantErr: "unknown pre-release rule"},
+		{name: "build commands", cfg: config.Config{PreBuildCommand: "pnpm run codegen", BuildCommand: "pnpm run vscode:prepublish", PackageArgs: "--no-dependencies", NodeVersion: "20.x"}},
+		{name: "node lts codename", cfg: config.Config{NodeVersion: "lts/iron"}},
+		{name: "invalid node version", cfg: config.Config{NodeVersion: "20; rm -rf /"}, wantErr: "invalid Node.js version"},
+		{name: "build command with scope", cfg: config.Config{BuildCommand: "make", BuildScope: "extension"}, wantErr: "cannot be combined"},
+		{name: "reserved package arg", cfg: config.Config{PackageArgs: "--no-dependencies --target=linux-x64"}, wantErr: "package argument --target"},
+		{name: "sbom", cfg: config.Config{SBOMFormat: "spdx"}},
+		{name: "unknown sbom format", cfg: config.Config{SBOMFormat: "syft"}, wantErr: "unknown SBOM format"},
+		{name: "invalid pre-release branch", cfg: config.Config{PreReleaseBranches: []string{"next..1"}}, wantErr: "invalid pre-release branch"},
+		{name: "conflict policy", cfg: config.Config{ConflictPolicies: []config.ConflictPolicy{{Path: "*.md", Policy: "mine"}}}, wantErr: "unknown conflict policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    config.ConflictPolicy
+		wantErr string
+	}{
+		{in: "README.md=ours", want: config.ConflictPolicy{Path: "README.md", Policy: "ours"}},
+		{in: "pnpm-lock.yaml=regenerate-lockfile", want: config.ConflictPolicy{Path: "pnpm-lock.yaml", Policy: "regenerate-lockfile"}},
+		{in: "package.json=json-field-merge:publisher,name", want: config.ConflictPolicy{Path: "package.json", Policy: "json-field-merge", Fields: []string{"publisher", "name"}}},
+		{in: "README.md", wantErr: "expected <glob>=<policy>"},
+		{in: "README.md=mine", wantErr: "unknown conflict policy"},
+		{in: "README.md=ours:title", wantErr: "does not take fields"},
+		{in: "src/[a=theirs", wantErr: "invalid conflict policy pattern"},
+	}
+
+	for _, tt := range tests {
+		got, err := config.ParseConflictPolicy(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseConflictPolicy(%q) expected error containing %q, got %v", tt.in, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseConflictPolicy(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseRegistry(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    config.Registry
+		wantErr string
+	}{
+		{in: "https://vsx.example.com/=COMPANY_VSX_TOKEN", want: config.Registry{URL: "https://vsx.example.com", TokenSecret: "COMPANY_VSX_TOKEN"}},
+		{in: "https://example.com/vsx?a=b=TOKEN", want: config.Registry{URL: "https://example.com/vsx?a=b", TokenSecret: "TOKEN"}},
+		{in: "https://vsx.example.com", wantErr: "expected <url>=<secret>"},
+		{in: "vsx.example.com=TOKEN", wantErr: "invalid registry URL"},
+		{in: "https://vsx.example.com=GITHUB_TOKEN", wantErr: "invalid token secret name"},
+	}
+
+	for _, tt := range tests {
+		got, err := config.ParseRegistry(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseRegistry(%q) expected error containing %q, got %v", tt.in, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRegistry(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultPolicies(t *testing.T) {
+	cfg := &config.Config{ExtensionPath: "./packages/ext"}
+	for file, want := range map[string]string{
+		".github/workflows/ovsx-fork-tools-sync.yml": config.PolicyOurs,
+		".ovsx-fork/config.json":                     config.PolicyOurs,
+		"packages/ext/package.json":                  config.PolicyJSONFieldMerge,
+		"package.json":                               "",
+		"src/extension.ts":                           "",
+	} {
+		p, _ := cfg.Policy(file)
+		if p.Policy != want {
+			t.Errorf("Policy(%q) = %q, want %q", file, p.Policy, want)
+		}
+	}
+
+	// Configured policies take precedence, and the defaults still cover the other fork-managed files
+	cfg.ConflictPolicies = []config.ConflictPolicy{
+		{Path: "README.md", Policy: config.PolicyOurs},
+		{Path: "packages/ext/package.json", Policy: config.PolicyTheirs},
+	}
+	for file, want := range map[string]string{
+		"README.md": config.PolicyOurs,
+		".github/workflows/ovsx-fork-tools-sync.yml": config.PolicyOurs,
+		".ovsx-fork/config.json":                     config.PolicyOurs,
+		"packages/ext/package.json":                  config.PolicyTheirs,
+		"src/extension.ts":                           "",
+	} {
+		p, _ := cfg.Policy(file)
+		if p.Policy != want {
+			t.Errorf("with configured policies, Policy(%q) = %q, want %q", file, p.Policy, want)
+		}
+	}
+}
+
+func TestInScope(t *testing.T) {
+	cfg := &config.Config{SyncPaths: []string{"./packages/ext/"}}
+	for file, want := range map[string]bool{
+		"packages/ext/package.json":   true,
+		"packages/ext/src/main.ts":    true,
+		"packages/extension/index.ts": false,
+		"packages/other/package.json": false,
+		"pnpm-lock.yaml":              true,
+		"package.json":                true,
+		"README.md":                   false,
+	} {
+		if got := cfg.InScope(file); got != want {
+			t.Errorf("InScope(%q) = %t, want %t", file, got, want)
+		}
+	}
+
+	if !(&config.Config{}).InScope("README.md") {
+		t.Error("every path should be in scope without sync paths")
+	}
+}
+
+func TestScopePaths(t *testing.T) {
+	cfg := &config.Config{SyncPaths: []string{"packages/ext"}, SharedFiles: []string{"pnpm-lock.yaml"}}
+	if got, want := cfg.ScopePaths(), []string{"packages/ext", "pnpm-lock.yaml"}; !slices.Equal(got, want) {
+		t.Errorf("ScopePaths() = %v, want %v", got, want)
+	}
+
+	cfg = &config.Config{SyncPaths: []string{"packages/ext"}}
+	if got, want := cfg.ScopePaths(), append([]string{"packages/ext"}, config.DefaultSharedFiles...); !slices.Equal(got, want) {
+		t.Errorf("ScopePaths() = %v, want %v", got, want)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		branch  string
+		version string
+		want    string
+	}{
+		{name: "default", branch: "main", version: "1.3.0", want: config.ChannelStable},
+		{name: "pre-release branch", cfg: config.Config{PreReleaseBranches: []string{"preview/*"}}, branch: "preview/next", version: "1.2.0", want: config.ChannelPreRelease},
+		{name: "other branch", cfg: config.Config{PreReleaseBranches: []string{"preview/*"}}, branch: "main", version: "1.2.0", want: config.ChannelStable},
+		{name: "odd minor", cfg: config.Config{PreReleaseRule: config.PreReleaseOddMinor}, branch: "main", version: "1.3.2001", want: config.ChannelPreRelease},
+		{name: "even minor", cfg: config.Config{PreReleaseRule: config.PreReleaseOddMinor}, branch: "main", version: "1.4.0", want: config.ChannelStable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.Channel(tt.branch, tt.version)
+			if err != nil || got != tt.want {
+				t.Errorf("Channel(%q, %q) = %q, %v, want %q", tt.branch, tt.version, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleases(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string
+	}{
+		{name: "default", want: nil},
+		{name: "base branch", cfg: config.Config{BaseBranch: "develop"}, want: []string{"develop"}},
+		{name: "pre-release branches", cfg: config.Config{PreReleaseBranches: []string{"next"}}, want: []string{"main", "master", "next"}},
+		{name: "release and pre-release branches", cfg: config.Config{ReleaseBranches: []string{"main", "next"}, PreReleaseBranches: []string{"next", "preview/*"}}, want: []string{"main", "next", "preview/*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.Releases(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Releases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
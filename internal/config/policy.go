@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/glob"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+)
+
+// Conflict resolution policies applied by the sync command.
+const (
+	// PolicyOurs keeps the fork's version of the file.
+	PolicyOurs = "ours"
+	// PolicyTheirs takes upstream's version of the file.
+	PolicyTheirs = "theirs"
+	// PolicyRegenerateLockfile takes upstream's lockfile and regenerates it with the package manager.
+	PolicyRegenerateLockfile = "regenerate-lockfile"
+	// PolicyJSONFieldMerge merges a JSON file field by field, keeping the fork's values of the
+	// listed fields. Other top-level fields changed on both sides are left in conflict.
+	PolicyJSONFieldMerge = "json-field-merge"
+)
+
+// DefaultMergeFields are the fields kept by the json-field-merge policy when none are listed.
+// They are the fields a fork typically rebrands.
+var DefaultMergeFields = []string{"name", "displayName", "publisher", "repository", "bugs", "homepage"}
+
+// ConflictPolicy resolves merge conflicts in files matching Path.
+type ConflictPolicy struct {
+	// Path is a glob pattern, see package glob.
+	Path   string `json:"path"`
+	Policy string `json:"policy"`
+	// Fields are the top-level fields kept from the fork by the json-field-merge policy.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ParseConflictPolicy parses a policy written as "<glob>=<policy>[:<field>,<field>...]",
+// for example "package.json=json-field-merge:publisher,name".
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	pattern, rest, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" {
+		return ConflictPolicy{}, fmt.Errorf("invalid conflict policy %q (expected <glob>=<policy>)", s)
+	}
+	policy, fields, _ := strings.Cut(rest, ":")
+
+	p := ConflictPolicy{Path: pattern, Policy: policy}
+	if fields != "" {
+		p.Fields = strings.Split(fields, ",")
+	}
+	return p, p.Validate()
+}
+
+// Validate checks the policy name, pattern and fields.
+func (p ConflictPolicy) Validate() error {
+	switch p.Policy {
+	case PolicyOurs, PolicyTheirs, PolicyRegenerateLockfile, PolicyJSONFieldMerge:
+	default:
+		return fmt.Errorf("unknown conflict policy %q for %s", p.Policy, p.Path)
+	}
+	if err := glob.Validate(p.Path); err != nil {
+		return fmt.Errorf("invalid conflict policy pattern %q: %w", p.Path, err)
+	}
+	if len(p.Fields) > 0 && p.Policy != PolicyJSONFieldMerge {
+		return fmt.Errorf("conflict policy %q for %s does not take fields", p.Policy, p.Path)
+	}
+	return nil
+}
+
+// MergeFields returns the fields kept from the fork by the json-field-merge policy.
+func (p ConflictPolicy) MergeFields() []string {
+	if len(p.Fields) == 0 {
+		return DefaultMergeFields
+	}
+	return p.Fields
+}
+
+// Policies returns the configured conflict policies followed by the defaults covering the
+// files the tool itself manages. Configured policies come first, so they take precedence
+// for the files they match, and the defaults still protect the fork-managed files they don't.
+func (c *Config) Policies() []ConflictPolicy {
+	return append(slices.Clone(c.ConflictPolicies),
+		ConflictPolicy{Path: ".github/workflows/ovsx-fork-tools-*.yml", Policy: PolicyOurs},
+		ConflictPolicy{Path: ".ovsx-fork/**", Policy: PolicyOurs},
+		ConflictPolicy{Path: "/" + path.Join(tag.CleanPath(c.ExtensionDir()), "package.json"), Policy: PolicyJSONFieldMerge},
+	)
+}
+
+// Policy returns the first conflict policy matching file.
+func (c *Config) Policy(file string) (ConflictPolicy, bool) {
+	for _, p := range c.Policies() {
+		if glob.Match(p.Path, file) {
+			return p, true
+		}
+	}
+	return ConflictPolicy{}, false
+}
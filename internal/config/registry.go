@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+)
+
+// MarketplaceTokenSecret is the secret holding the VS Marketplace personal access token.
+const MarketplaceTokenSecret = "VSCE_PAT"
+
+// DefaultRegistry is the publish target when no registries are configured.
+var DefaultRegistry = Registry{URL: openvsx.DefaultURL, TokenSecret: "OPEN_VSX_TOKEN"}
+
+// Registry is an OpenVSX instance the release workflow publishes to.
+type Registry struct {
+	URL string `json:"url"`
+	// TokenSecret names the secret holding the publisher's access token for the registry.
+	TokenSecret string `json:"tokenSecret"`
+}
+
+// ParseRegistry parses a registry of the form "<url>=<secret>".
+func ParseRegistry(s string) (Registry, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return Registry{}, fmt.Errorf("invalid registry %q (expected <url>=<secret>)", s)
+	}
+	r := Registry{URL: strings.TrimSuffix(s[:i], "/"), TokenSecret: s[i+1:]}
+	return r, r.Validate()
+}
+
+// Validate checks the registry URL and secret name.
+func (r Registry) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid registry URL %q: expected an http(s) URL", r.URL)
+	}
+	if !validSecretName(r.TokenSecret) {
+		return fmt.Errorf("invalid token secret name %q for %s: use letters, digits and underscores, not starting with a digit or GITHUB_", r.TokenSecret, r.URL)
+	}
+	return nil
+}
+
+// Host returns the registry's host name, used to label its publish step.
+func (r Registry) Host() string {
+	if u, err := url.Parse(r.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return r.URL
+}
+
+// PublishTargets returns the registries to publish to, defaulting to DefaultRegistry.
+func (c *Config) PublishTargets() []Registry {
+	if len(c.Registries) == 0 {
+		return []Registry{DefaultRegistry}
+	}
+	return c.Registries
+}
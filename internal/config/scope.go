@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+)
+
+// Build scopes of the release workflow.
+const (
+	// BuildScopeAll builds every workspace package.
+	BuildScopeAll = "all"
+	// BuildScopeExtension builds only the extension's package and the workspace packages it depends on.
+	BuildScopeExtension = "extension"
+)
+
+// DefaultSharedFiles are the repository root files merged along with the sync paths when
+// none are configured. They describe the workspace as a whole, so the extension's
+// dependencies cannot be updated without them.
+var DefaultSharedFiles = []string{"package.json", "pnpm-lock.yaml", "pnpm-workspace.yaml", "package-lock.json", "yarn.lock", ".npmrc"}
+
+// Scoped reports whether the sync only merges the configured paths.
+func (c *Config) Scoped() bool {
+	return len(c.SyncPaths) > 0
+}
+
+// InScope reports whether upstream changes to a repository path are merged.
+// Every path is in scope when no sync paths are configured.
+func (c *Config) InScope(file string) bool {
+	if !c.Scoped() {
+		return true
+	}
+	for _, p := range c.SyncPaths {
+		p = tag.CleanPath(p)
+		if p == "." || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return slices.Contains(c.shared(), file)
+}
+
+// ScopePaths returns the pathspecs of everything a scoped sync merges: the sync paths
+// and the shared files.
+func (c *Config) ScopePaths() []string {
+	return append(slices.Clone(c.SyncPaths), c.shared()...)
+}
+
+// shared returns the configured shared files, or DefaultSharedFiles when none are.
+func (c *Config) shared() []string {
+	if len(c.SharedFiles) == 0 {
+		return DefaultSharedFiles
+	}
+	return c.SharedFiles
+}
+
+// validateSyncPath checks that p is a relative path inside the repository.
+func validateSyncPath(p string) error {
+	clean := tag.CleanPath(p)
+	if clean == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || path.Clean(clean) != clean {
+		return fmt.Errorf("invalid sync path %q: expected a relative path inside the repository", p)
+	}
+	return nil
+}
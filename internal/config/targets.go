@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TargetUniversal is the platform-independent package, installed on platforms without
+// a package of their own. It is always built alongside the configured targets.
+const TargetUniversal = "universal"
+
+// ValidTargets are the target platforms VS Code, vsce and OpenVSX accept.
+var ValidTargets = []string{
+	"win32-x64", "win32-arm64",
+	"linux-x64", "linux-arm64", "linux-armhf",
+	"alpine-x64", "alpine-arm64",
+	"darwin-x64", "darwin-arm64",
+	"web",
+}
+
+// ParseTargets splits a comma-separated list of target platforms.
+func ParseTargets(s string) []string {
+	var targets []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// validateTargets checks that each target is a known platform and listed once.
+func validateTargets(targets []string) error {
+	seen := map[string]bool{}
+	for _, t := range targets {
+		switch {
+		case t == TargetUniversal:
+			return fmt.Errorf("target %q is always built and must not be listed", TargetUniversal)
+		case !slices.Contains(ValidTargets, t):
+			return fmt.Errorf("unknown target %q (expected one of %s)", t, strings.Join(ValidTargets, ", "))
+		case seen[t]:
+			return fmt.Errorf("target %q is listed twice", t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
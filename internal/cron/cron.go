@@ -0,0 +1,91 @@
+// Package cron validates the POSIX cron expressions accepted by GitHub Actions schedules.
+//
+// An expression has five space-separated fields: minute, hour, day of month, month and
+// day of week. Each field is "*" or a comma-separated list of values, ranges ("1-5") and
+// steps ("*/15", "0-30/5"). Months and days of the week may also be given by their
+// three-letter English names ("JAN", "MON").
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 6, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// Validate checks that expr is a valid schedule expression.
+func Validate(expr string) error {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(parts))
+	}
+	for i, part := range parts {
+		for _, item := range strings.Split(part, ",") {
+			if err := fields[i].check(item); err != nil {
+				return fmt.Errorf("invalid cron expression %q: %s field: %w", expr, fields[i].name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// check validates one list item of the field.
+func (f field) check(item string) error {
+	rng, step, hasStep := strings.Cut(item, "/")
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid step %q", step)
+		}
+	}
+
+	if rng == "*" {
+		return nil
+	}
+	lo, hi, isRange := strings.Cut(rng, "-")
+	from, err := f.value(lo)
+	if err != nil {
+		return err
+	}
+	if !isRange {
+		return nil
+	}
+	to, err := f.value(hi)
+	if err != nil {
+		return err
+	}
+	if from > to {
+		return fmt.Errorf("range %q is reversed", rng)
+	}
+	return nil
+}
+
+// value parses a number or name within the field's bounds.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%d is out of range %d-%d", n, f.min, f.max)
+	}
+	return n, nil
+}
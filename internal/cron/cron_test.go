@@ -0,0 +1,41 @@
+package cron_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/cron"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "0 3 * * *"},
+		{expr: "*/15 * * * *"},
+		{expr: "0 0-12/4 1,15 * MON-FRI"},
+		{expr: "30 6 * jan,jul sun"},
+		{expr: "0 3 * *", wantErr: "expected 5 fields"},
+		{expr: "60 3 * * *", wantErr: "minute field: 60 is out of range 0-59"},
+		{expr: "0 3 0 * *", wantErr: "day of month field"},
+		{expr: "0 3 * * 7", wantErr: "day of week field"},
+		{expr: "0 12-6 * * *", wantErr: "reversed"},
+		{expr: "*/0 * * * *", wantErr: "invalid step"},
+		{expr: "0 3 * FOO *", wantErr: `invalid value "FOO"`},
+		{expr: "@daily", wantErr: "expected 5 fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			err := cron.Validate(tt.expr)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package diverge implements the diverge command, which reports how far the fork has
+// drifted from upstream.
+//
+// Usage:
+//
+//	ovsx-setup diverge [--json] [--registry <url>]
+//
+// The report lists how many upstream commits the fork is behind, the fork-only commits
+// it is ahead by, the files the fork modifies compared with the upstream revision it is
+// based on, upstream release tags the fork has not released yet, and the version last
+// published to OpenVSX.
+package diverge
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// Report describes the divergence between the fork's HEAD and upstream.
+type Report struct {
+	Target *sync.Target `json:"target"`
+	// Base is the upstream revision the fork is based on.
+	Base   string `json:"base"`
+	Behind int    `json:"behind"`
+	Ahead  int    `json:"ahead"`
+	// Commits are the fork-only commits, or the patch series with the patches sync strategy.
+	Commits  []git.Commit `json:"commits"`
+	Modified []FileChange `json:"modified"`
+	// Unreleased are upstream tags newer than the fork's last release, highest first.
+	Unreleased []string `json:"unreleased"`
+	Published  string   `json:"published,omitempty"`
+	// PublishedError explains why the published version could not be determined.
+	PublishedError string `json:"publishedError,omitempty"`
+}
+
+// FileChange is a file the fork changes compared with upstream.
+type FileChange struct {
+	// Status is git's status letter: A (added), M (modified), D (deleted), R (renamed)...
+	Status string `json:"status"`
+	Path   string `json:"path"`
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("diverge", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	registry := fs.String("registry", openvsx.DefaultURL, "OpenVSX registry to look up the published version in")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	url, err := sync.DetectURL(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Fetching upstream %s\n", url)
+
+	target, err := sync.Fetch(cfg, url)
+	if err != nil {
+		return err
+	}
+
+	report, err := Build(cfg, target)
+	if err != nil {
+		return err
+	}
+
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+	if ext, err := openvsx.New(*registry).Extension(cfg.Publisher, m.Name, ""); err != nil {
+		report.PublishedError = err.Error()
+	} else {
+		report.Published = ext.Version
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.WriteText(os.Stdout)
+	return nil
+}
+
+// Build compares HEAD with the fetched target. The published version is left for the caller.
+func Build(cfg *config.Config, target *sync.Target) (*Report, error) {
+	report := &Report{Target: target}
+	base, patches, err := sync.Base(cfg, target)
+	if err != nil {
+		return nil, err
+	}
+	report.Base = base
+
+	if report.Behind, err = count(base + ".." + target.Ref); err != nil {
+		return nil, err
+	}
+
+	if patches {
+		names, err := git.Lines("ls-tree", "--name-only", "HEAD", config.PatchDir+"/")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if strings.HasSuffix(name, ".patch") {
+				report.Commits = append(report.Commits, git.Commit{Subject: path.Base(name)})
+			}
+		}
+	} else if report.Commits, err = git.Log("--no-merges", "HEAD", "--not", target.Ref); err != nil {
+		return nil, err
+	}
+	report.Ahead = len(report.Commits)
+
+	lines, err := git.Lines("diff", "--name-status", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		report.Modified = append(report.Modified, FileChange{Status: fields[0][:1], Path: fields[len(fields)-1]})
+	}
+
+	if report.Unreleased, err = unreleased(cfg); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func count(revs string) (int, error) {
+	out, err := git.Output("rev-list", "--count", revs)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	_, err = fmt.Sscan(out, &n)
+	return n, err
+}
+
+// unreleased returns the upstream tags, highest first, newer than both the fork's last
+// released upstream version and the upstream version HEAD is based on.
+func unreleased(cfg *config.Config) ([]string, error) {
+	tags, err := sync.Tags(cfg.UpstreamTagPattern)
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return nil, err
+	}
+	current, err := version.Parse(m.Version)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.VersionScheme == version.SchemeRevision {
+		// A fork revision in the manifest stands for the upstream version it was built from
+		if upstream, _, err := version.Upstream(current, cfg.Digits()); err == nil {
+			current = upstream
+		}
+	}
+	forkTags, err := git.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var unreleased []string
+	for _, t := range tags {
+		v, err := sync.TagVersion(t)
+		if err != nil {
+			continue
+		}
+		if v.Compare(current) < 0 || released(cfg, m.Name, v, forkTags) {
+			break
+		}
+		unreleased = append(unreleased, t)
+	}
+	return unreleased, nil
+}
+
+// released reports whether the fork has a release tag for upstream version v.
+func released(cfg *config.Config, name string, v version.Version, tags map[string]bool) bool {
+	if cfg.VersionScheme != version.SchemeRevision {
+		return tags[cfg.Tag(name, v.String())]
+	}
+	fork, err := version.Fork(v, 0, cfg.Digits())
+	return err == nil && tags[cfg.Tag(name, fork.String())]
+}
+
+// WriteText writes a human-readable report.
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Upstream: %s (%s)\n", r.Target.URL, r.Target.Ref)
+	fmt.Fprintf(w, "Based on: %.12s\n", r.Base)
+	fmt.Fprintf(w, "Behind: %d upstream commit(s)\n", r.Behind)
+	fmt.Fprintf(w, "Ahead: %d fork commit(s)\n", r.Ahead)
+	for _, c := range r.Commits {
+		if c.Hash == "" {
+			fmt.Fprintf(w, "  %s\n", c.Subject)
+		} else {
+			fmt.Fprintf(w, "  %s %s\n", c.Hash, c.Subject)
+		}
+	}
+
+	fmt.Fprintf(w, "\nModified files (%d):\n", len(r.Modified))
+	for _, f := range r.Modified {
+		fmt.Fprintf(w, "  %s %s\n", f.Status, f.Path)
+	}
+
+	if len(r.Unreleased) == 0 {
+		fmt.Fprintln(w, "\nUnreleased upstream tags: none")
+	} else {
+		fmt.Fprintf(w, "\nUnreleased upstream tags: %s\n", strings.Join(r.Unreleased, ", "))
+	}
+
+	if r.Published != "" {
+		fmt.Fprintf(w, "Published on OpenVSX: %s\n", r.Published)
+	} else {
+		fmt.Fprintf(w, "Published on OpenVSX: unknown (%s)\n", r.PublishedError)
+	}
+}
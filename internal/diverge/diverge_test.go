@@ -0,0 +1,76 @@
+package diverge_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/diverge"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gittest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+)
+
+func TestBuild(t *testing.T) {
+	gittest.Identity(t)
+
+	root := t.TempDir()
+	upstream := filepath.Join(root, "upstream")
+	fork := filepath.Join(root, "fork")
+
+	gittest.Run(t, root, "init", "-q", "-b", "main", upstream)
+	gittest.CommitFile(t, upstream, "package.json", `{"name": "ext", "version": "1.0.0"}`, "initial")
+	gittest.Run(t, upstream, "tag", "v1.0.0")
+
+	// The clone keeps v1.0.0, standing in for the fork's release of it
+	gittest.Run(t, root, "clone", "-q", upstream, fork)
+	gittest.Run(t, fork, "remote", "remove", "origin")
+	gittest.CommitFile(t, fork, "README.md", "fork\n", "docs: describe the fork")
+
+	gittest.CommitFile(t, upstream, "package.json", `{"name": "ext", "version": "1.1.0"}`, "chore: release 1.1.0")
+	gittest.Run(t, upstream, "tag", "v1.1.0")
+	gittest.CommitFile(t, upstream, "package.json", `{"name": "ext", "version": "1.2.0"}`, "chore: release 1.2.0")
+	gittest.Run(t, upstream, "tag", "v1.2.0")
+	t.Chdir(fork)
+
+	cfg := &config.Config{UpstreamURL: upstream}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err := diverge.Build(cfg, target)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if report.Behind != 2 || report.Ahead != 1 {
+		t.Errorf("expected 2 behind and 1 ahead, got %d and %d", report.Behind, report.Ahead)
+	}
+	if len(report.Commits) != 1 || report.Commits[0].Subject != "docs: describe the fork" {
+		t.Errorf("unexpected fork commits %+v", report.Commits)
+	}
+	if !slices.Equal(report.Modified, []diverge.FileChange{{Status: "A", Path: "README.md"}}) {
+		t.Errorf("unexpected modified files %+v", report.Modified)
+	}
+	if !slices.Equal(report.Unreleased, []string{"v1.2.0", "v1.1.0"}) {
+		t.Errorf("unexpected unreleased tags %v", report.Unreleased)
+	}
+
+	// Under the revision scheme the manifest holds the fork version of the upstream release
+	gittest.Run(t, fork, "merge", "-q", target.Ref)
+	gittest.CommitFile(t, fork, "package.json", `{"name": "ext", "version": "1.2.1000"}`, "chore: release 1.2.1000")
+	gittest.Run(t, fork, "tag", "v1.2.1000")
+	gittest.CommitFile(t, upstream, "package.json", `{"name": "ext", "version": "1.2.1"}`, "fix: release 1.2.1")
+	gittest.Run(t, upstream, "tag", "v1.2.1")
+
+	cfg = &config.Config{UpstreamURL: upstream, VersionScheme: "revision"}
+	if target, err = sync.Fetch(cfg, upstream); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if report, err = diverge.Build(cfg, target); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if !slices.Equal(report.Unreleased, []string{"v1.2.1"}) {
+		t.Errorf("unexpected unreleased tags under the revision scheme %v", report.Unreleased)
+	}
+}
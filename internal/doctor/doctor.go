@@ -0,0 +1,138 @@
+// Package doctor implements the doctor command, which checks that the fork is ready to release.
+//
+// Usage:
+//
+//	ovsx-setup doctor
+//
+// It validates the configuration and checks that the repository has every secret the
+// configured workflows read: a token for each registry, the VS Marketplace token when
+// marketplace publishing is enabled, and the sync workflow's GitHub App key or token.
+// It also checks that the package.json scripts the pre-build and build commands run exist.
+// Each check is printed with how to fix it, and the command fails if any check fails.
+package doctor
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gh"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+)
+
+// runScript matches the scripts a command runs with 'npm run', 'pnpm run' or 'yarn run'.
+var runScript = regexp.MustCompile(`(?:^|[\s;&|(])(?:npm|pnpm|yarn)\s+run\s+["']?([\w:.-]+)`)
+
+// Finding is the result of a single check.
+type Finding struct {
+	OK      bool
+	Message string
+	// Fix is how to resolve a failed check.
+	Fix string
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	repo, err := gh.Repo()
+	if err != nil {
+		return err
+	}
+	secrets, err := gh.Secrets(repo)
+	if err != nil {
+		return fmt.Errorf("failed to list the secrets of %s: %w", repo, err)
+	}
+
+	findings := append(Check(cfg, secrets), CheckBuild(cfg, ".")...)
+	failed := Write(os.Stdout, findings)
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// Check runs the checks against the repository's secrets.
+func Check(cfg *config.Config, secrets map[string]bool) []Finding {
+	var findings []Finding
+	secret := func(name, purpose string) {
+		f := Finding{OK: secrets[name], Message: fmt.Sprintf("Secret %s (%s)", name, purpose)}
+		if !f.OK {
+			f.Fix = fmt.Sprintf("gh secret set %s", name)
+		}
+		findings = append(findings, f)
+	}
+
+	for _, r := range cfg.PublishTargets() {
+		secret(r.TokenSecret, "token for "+r.URL)
+	}
+	if cfg.Marketplace != nil && *cfg.Marketplace {
+		secret(config.MarketplaceTokenSecret, "VS Marketplace personal access token")
+	}
+	if cfg.AppID != "" {
+		secret(config.AppKeySecret, "private key of GitHub App "+cfg.AppID)
+	} else if cfg.TokenSecret != "" {
+		secret(cfg.TokenSecret, "sync workflow token")
+	}
+	return findings
+}
+
+// CheckBuild checks that the scripts run by the pre-build and build commands are defined
+// in the package.json at the root of the repository in dir, or in the extension's.
+func CheckBuild(cfg *config.Config, dir string) []Finding {
+	scripts := map[string]bool{}
+	for _, d := range []string{dir, filepath.Join(dir, cfg.ExtensionDir())} {
+		if m, err := manifest.Read(d); err == nil {
+			for name := range m.Scripts {
+				scripts[name] = true
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, c := range []struct{ name, command string }{
+		{"pre-build command", cfg.PreBuildCommand},
+		{"build command", cfg.BuildCommand},
+	} {
+		for _, match := range runScript.FindAllStringSubmatch(c.command, -1) {
+			f := Finding{OK: scripts[match[1]], Message: fmt.Sprintf("Script %q run by the %s", match[1], c.name)}
+			if !f.OK {
+				f.Fix = fmt.Sprintf("add a %q script to package.json, or change the %s", match[1], c.name)
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Write prints the findings and returns how many failed.
+func Write(w io.Writer, findings []Finding) int {
+	failed := 0
+	for _, f := range findings {
+		if f.OK {
+			fmt.Fprintf(w, "✅ %s\n", f.Message)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "❌ %s\n", f.Message)
+		if f.Fix != "" {
+			fmt.Fprintf(w, "   Fix: %s\n", f.Fix)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,104 @@
+package doctor_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/doctor"
+)
+
+func TestCheck(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		secrets map[string]bool
+		failed  []string
+	}{
+		{
+			name:    "defaults",
+			cfg:     &config.Config{},
+			secrets: map[string]bool{"OPEN_VSX_TOKEN": true},
+		},
+		{
+			name:   "missing registry token",
+			cfg:    &config.Config{},
+			failed: []string{"OPEN_VSX_TOKEN"},
+		},
+		{
+			name: "marketplace and registries",
+			cfg: &config.Config{
+				Registries:  []config.Registry{{URL: "https://vsx.example.com", TokenSecret: "COMPANY_VSX_TOKEN"}},
+				Marketplace: &enabled,
+			},
+			secrets: map[string]bool{"OPEN_VSX_TOKEN": true},
+			failed:  []string{"COMPANY_VSX_TOKEN", "VSCE_PAT"},
+		},
+		{
+			name:    "github app",
+			cfg:     &config.Config{AppID: "123", TokenSecret: "RELEASE_PAT"},
+			secrets: map[string]bool{"OPEN_VSX_TOKEN": true},
+			failed:  []string{config.AppKeySecret},
+		},
+		{
+			name:    "token secret",
+			cfg:     &config.Config{TokenSecret: "RELEASE_PAT"},
+			secrets: map[string]bool{"OPEN_VSX_TOKEN": true, "RELEASE_PAT": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := doctor.Check(tt.cfg, tt.secrets)
+			var out bytes.Buffer
+			if failed := doctor.Write(&out, findings); failed != len(tt.failed) {
+				t.Errorf("expected %d failed checks, got %d:\n%s", len(tt.failed), failed, out.String())
+			}
+			for _, name := range tt.failed {
+				if !strings.Contains(out.String(), "gh secret set "+name) {
+					t.Errorf("expected a fix for %s:\n%s", name, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCheckBuild(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"codegen": "node gen.js"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ext := filepath.Join(dir, "packages", "ext")
+	if err := os.MkdirAll(ext, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ext, "package.json"), []byte(`{"scripts": {"vscode:prepublish": "tsc"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		ExtensionPath:   "packages/ext",
+		PreBuildCommand: "pnpm run codegen && pnpm run assets",
+		BuildCommand:    `cd packages/ext && npm run "vscode:prepublish"`,
+	}
+	var out bytes.Buffer
+	if failed := doctor.Write(&out, doctor.CheckBuild(cfg, dir)); failed != 1 {
+		t.Errorf("expected 1 failed check, got %d:\n%s", failed, out.String())
+	}
+	for _, want := range []string{
+		`✅ Script "codegen" run by the pre-build command`,
+		`❌ Script "assets" run by the pre-build command`,
+		`✅ Script "vscode:prepublish" run by the build command`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if findings := doctor.CheckBuild(&config.Config{}, dir); len(findings) != 0 {
+		t.Errorf("expected no build checks without build commands, got %v", findings)
+	}
+}
@@ -0,0 +1,64 @@
+// Package gh runs GitHub CLI commands for the current repository.
+package gh
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Output runs gh with the given arguments and returns its trimmed standard output.
+// On failure the returned error includes gh's standard error.
+func Output(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("gh", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("gh %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Repo returns the "owner/name" of the current repository.
+func Repo() (string, error) {
+	repo, err := Output("repo", "view", "--json", "nameWithOwner", "--jq", ".nameWithOwner")
+	if err != nil {
+		return "", err
+	}
+	if repo == "" {
+		return "", fmt.Errorf("could not determine the GitHub repository")
+	}
+	return repo, nil
+}
+
+// Secrets returns the names of the repository's Actions secrets.
+func Secrets(repo string) (map[string]bool, error) {
+	out, err := Output("secret", "list", "--repo", repo, "--json", "name", "--jq", ".[].name")
+	if err != nil {
+		return nil, err
+	}
+	secrets := map[string]bool{}
+	for _, name := range strings.Fields(out) {
+		secrets[name] = true
+	}
+	return secrets, nil
+}
+
+// Comment creates the pull request (or issue) comment starting with marker, or updates it
+// when a previous run created one, so that repeated runs keep a single comment.
+func Comment(repo string, number int, marker, body string) error {
+	body = marker + "\n" + body
+	comments := fmt.Sprintf("repos/%s/issues/%d/comments", repo, number)
+	ids, err := Output("api", comments, "--paginate", "--jq", fmt.Sprintf(".[] | select(.body | startswith(%q)) | .id", marker))
+	if err != nil {
+		return err
+	}
+	if id, _, _ := strings.Cut(ids, "\n"); id != "" {
+		_, err = Output("api", "-X", "PATCH", fmt.Sprintf("repos/%s/issues/comments/%s", repo, id), "-f", "body="+body)
+		return err
+	}
+	_, err = Output("api", comments, "-f", "body="+body)
+	return err
+}
@@ -0,0 +1,77 @@
+// Package git runs git commands in the current repository.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Output runs git with the given arguments and returns its trimmed standard output.
+// On failure the returned error includes git's standard error.
+func Output(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Lines runs git and splits its output into non-empty lines.
+func Lines(args ...string) ([]string, error) {
+	out, err := Output(args...)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// IsAncestor reports whether commit a is reachable from commit b. It is false when either
+// ref does not exist.
+func IsAncestor(a, b string) bool {
+	_, err := Output("merge-base", "--is-ancestor", a, b)
+	return err == nil
+}
+
+// Tags returns the set of tags in the repository.
+func Tags() (map[string]bool, error) {
+	lines, err := Lines("tag", "-l")
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]bool, len(lines))
+	for _, t := range lines {
+		tags[t] = true
+	}
+	return tags, nil
+}
+
+// Commit is a commit summary as listed by Log.
+type Commit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+}
+
+// Log lists commits selected by the given git log arguments, newest first.
+func Log(args ...string) ([]Commit, error) {
+	lines, err := Lines(append([]string{"log", "--format=%h%x09%s"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]Commit, 0, len(lines))
+	for _, line := range lines {
+		hash, subject, _ := strings.Cut(line, "\t")
+		commits = append(commits, Commit{Hash: hash, Subject: subject})
+	}
+	return commits, nil
+}
@@ -0,0 +1,69 @@
+// Package gittest provides helpers for tests that run git in temporary repositories.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Identity sets the author and committer git uses for the rest of the test.
+func Identity(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+}
+
+// Run runs git in dir and fails the test when it fails.
+func Run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// Output runs git in dir and returns its trimmed standard output.
+func Output(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// WriteFile writes a file in dir, creating its parent directories.
+func WriteFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Commit stages every change in dir and commits it.
+func Commit(t *testing.T, dir, msg string) {
+	t.Helper()
+	Run(t, dir, "add", "-A")
+	Run(t, dir, "commit", "-q", "-m", msg)
+}
+
+// CommitFile writes a file in dir and commits every change.
+func CommitFile(t *testing.T, dir, name, content, msg string) {
+	t.Helper()
+	WriteFile(t, dir, name, content)
+	Commit(t, dir, msg)
+}
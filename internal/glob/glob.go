@@ -0,0 +1,52 @@
+// Package glob matches slash-separated repository paths against glob patterns.
+//
+// Patterns use the syntax of path.Match for each path segment, plus "**", which
+// matches any number of segments, including none. A pattern without a slash is
+// matched against the base name of the path, so "*.lock" matches at any depth.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Validate reports whether the pattern is well-formed.
+func Validate(pattern string) error {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match reports whether name matches pattern.
+func Match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
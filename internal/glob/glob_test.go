@@ -0,0 +1,41 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/glob"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"package.json", "package.json", true},
+		{"package.json", "packages/ext/package.json", true},
+		{"/package.json", "packages/ext/package.json", false},
+		{"/package.json", "package.json", true},
+		{"*.lock", "yarn.lock", true},
+		{".github/workflows/ovsx-fork-tools-*.yml", ".github/workflows/ovsx-fork-tools-sync.yml", true},
+		{".github/workflows/ovsx-fork-tools-*.yml", ".github/workflows/ci.yml", false},
+		{".ovsx-fork/**", ".ovsx-fork/patches/0001.patch", true},
+		{"packages/**/package.json", "packages/package.json", true},
+		{"packages/**/package.json", "packages/a/b/package.json", true},
+		{"packages/*/package.json", "packages/a/b/package.json", false},
+	}
+
+	for _, tt := range tests {
+		if got := glob.Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := glob.Validate("src/**/*.ts"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := glob.Validate("src/[a"); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
@@ -0,0 +1,165 @@
+// Package manifest reads and patches an extension's package.json.
+//
+// Patching preserves the order of the top-level fields so the packaged manifest
+// stays recognizable when compared with the upstream one.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// FileName is the name of the extension manifest.
+const FileName = "package.json"
+
+// Manifest holds the package.json fields used by the tool.
+type Manifest struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Publisher   string `json:"publisher"`
+	Version     string `json:"version"`
+
+	Scripts map[string]string `json:"scripts"`
+
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// Read parses the package.json in dir.
+func Read(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses package.json content.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", FileName, err)
+	}
+	return &m, nil
+}
+
+// Update sets top-level string fields of the package.json in dir.
+func Update(dir string, fields map[string]string) error {
+	raw := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		raw[key] = String(value)
+	}
+	return UpdateRaw(dir, raw)
+}
+
+// UpdateRaw sets top-level fields of the package.json in dir to raw JSON values.
+func UpdateRaw(dir string, fields map[string]json.RawMessage) error {
+	obj, err := ReadObject(dir)
+	if err != nil {
+		return err
+	}
+	for key, value := range fields {
+		obj.Set(key, value)
+	}
+
+	out, err := obj.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName), out, 0644)
+}
+
+// ReadObject parses the package.json in dir, keeping its top-level key order.
+func ReadObject(dir string) (*Object, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	return ParseObject(data)
+}
+
+// String encodes s as a JSON string without escaping HTML characters.
+func String(s string) json.RawMessage {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+// Object is a JSON object whose top-level key order is preserved.
+type Object struct {
+	Keys   []string
+	Values map[string]json.RawMessage
+}
+
+// ParseObject decodes a JSON object, keeping its top-level key order.
+func ParseObject(data []byte) (*Object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("invalid %s: expected a JSON object", FileName)
+	}
+
+	obj := &Object{Values: map[string]json.RawMessage{}}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", FileName, err)
+		}
+		key := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", FileName, err)
+		}
+		obj.Set(key, value)
+	}
+	return obj, nil
+}
+
+// Get returns the raw value of key, or nil if it is not present.
+func (o *Object) Get(key string) json.RawMessage {
+	return o.Values[key]
+}
+
+// Set replaces the value of key, appending it if it is not present.
+func (o *Object) Set(key string, value json.RawMessage) {
+	if _, ok := o.Values[key]; !ok {
+		o.Keys = append(o.Keys, key)
+	}
+	o.Values[key] = value
+}
+
+// Delete removes key if it is present.
+func (o *Object) Delete(key string) {
+	if _, ok := o.Values[key]; !ok {
+		return
+	}
+	o.Keys = slices.DeleteFunc(o.Keys, func(k string) bool { return k == key })
+	delete(o.Values, key)
+}
+
+// Marshal encodes the object with two-space indentation and a trailing newline.
+func (o *Object) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, key := range o.Keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+		buf.Write(String(key))
+		buf.WriteString(": ")
+		if err := json.Indent(&buf, o.Values[key], "  ", "  "); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString("\n}\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package manifest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+)
+
+func TestUpdatePreservesOrder(t *testing.T) {
+	dir := t.TempDir()
+	original := `{
+  "name": "ext",
+  "version": "1.2.3",
+  "engines": {
+    "vscode": "^1.80.0"
+  },
+  "publisher": "upstream"
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, manifest.FileName), []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manifest.Update(dir, map[string]string{"version": "1.2.3001", "publisher": "me & co"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(filepath.Join(dir, manifest.FileName))
+	want := `{
+  "name": "ext",
+  "version": "1.2.3001",
+  "engines": {
+    "vscode": "^1.80.0"
+  },
+  "publisher": "me & co"
+}
+`
+	if string(got) != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, want)
+	}
+
+	m, err := manifest.Read(dir)
+	if err != nil || m.Version != "1.2.3001" {
+		t.Errorf("Read = %+v, %v", m, err)
+	}
+}
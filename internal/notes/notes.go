@@ -0,0 +1,144 @@
+// Package notes implements the release-notes command, which writes the body of the
+// fork's GitHub Release.
+//
+// Usage:
+//
+//	ovsx-setup release-notes [--tag <tag>]
+//
+// When upstream is hosted on GitHub and has a release for the upstream version the fork
+// is built from, the body links to it and includes its notes. Otherwise it only names the
+// upstream version.
+package notes
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gh"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+var scpGitHubURL = regexp.MustCompile(`^git@github\.com:([^/]+/[^/]+?)(\.git)?$`)
+
+// Release is an upstream GitHub Release.
+type Release struct {
+	Tag  string
+	URL  string
+	Body string
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("release-notes", flag.ContinueOnError)
+	forkTag := fs.String("tag", "", "The fork's release tag, mentioned in the notes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+	// The fork version is never committed, so the manifest holds upstream's version
+	upstream, err := version.Parse(m.Version)
+	if err != nil {
+		return err
+	}
+
+	var release *Release
+	if u, err := sync.DetectURL(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not detect upstream, leaving out its release notes: %v\n", err)
+	} else if repo, ok := GitHubRepo(u); ok {
+		if release, err = Find(repo, upstream); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not get upstream's releases, leaving out their notes: %v\n", err)
+		}
+	}
+
+	Write(os.Stdout, *forkTag, upstream, release)
+	return nil
+}
+
+// GitHubRepo returns the "owner/name" of a GitHub repository URL.
+func GitHubRepo(u string) (string, bool) {
+	if m := scpGitHubURL.FindStringSubmatch(u); m != nil {
+		return m[1], true
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host != "github.com" {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(parsed.Path, ".git"), "/"), "/")
+	if len(parts) != 2 {
+		return "", false
+	}
+	return parts[0] + "/" + parts[1], true
+}
+
+// Find returns repo's release of version v, or nil when it has none.
+func Find(repo string, v version.Version) (*Release, error) {
+	out, err := gh.Output("api", "--paginate", fmt.Sprintf("repos/%s/releases", repo), "--jq", ".[] | select(.draft | not) | .tag_name")
+	if err != nil {
+		return nil, err
+	}
+	tag := Match(strings.Fields(out), v)
+	if tag == "" {
+		return nil, nil
+	}
+
+	out, err = gh.Output("api", fmt.Sprintf("repos/%s/releases/tags/%s", repo, url.PathEscape(tag)))
+	if err != nil {
+		return nil, err
+	}
+	var r struct {
+		URL  string `json:"html_url"`
+		Body string `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		return nil, fmt.Errorf("invalid release %s of %s: %w", tag, repo, err)
+	}
+	return &Release{Tag: tag, URL: r.URL, Body: r.Body}, nil
+}
+
+// Match returns the first tag naming version v, or "" when none does.
+func Match(tags []string, v version.Version) string {
+	for _, t := range tags {
+		if tv, err := sync.TagVersion(t); err == nil && tv.Compare(v) == 0 {
+			return t
+		}
+	}
+	return ""
+}
+
+// Write writes the release body.
+func Write(w io.Writer, forkTag string, upstream version.Version, release *Release) {
+	if forkTag != "" {
+		fmt.Fprintf(w, "Fork release %s of upstream version %s.\n", forkTag, upstream)
+	} else {
+		fmt.Fprintf(w, "Fork release of upstream version %s.\n", upstream)
+	}
+	if release == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "\n## Upstream Release Notes\n\nFrom [%s](%s):\n", release.Tag, release.URL)
+	if body := strings.TrimSpace(release.Body); body != "" {
+		fmt.Fprintf(w, "\n%s\n", body)
+	}
+}
@@ -0,0 +1,56 @@
+package notes_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/notes"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+func TestGitHubRepo(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{url: "https://github.com/owner/ext", want: "owner/ext", ok: true},
+		{url: "https://github.com/owner/ext.git", want: "owner/ext", ok: true},
+		{url: "git@github.com:owner/ext.git", want: "owner/ext", ok: true},
+		{url: "https://gitlab.com/owner/ext.git"},
+		{url: "https://github.com/owner"},
+	}
+	for _, tt := range tests {
+		got, ok := notes.GitHubRepo(tt.url)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("GitHubRepo(%q) = %q, %t, want %q, %t", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tags := []string{"nightly", "v1.3.0", "release/1.2.0", "v1.2.0-rc.1"}
+	if got := notes.Match(tags, version.Version{Major: 1, Minor: 2}); got != "release/1.2.0" {
+		t.Errorf("Match(1.2.0) = %q", got)
+	}
+	if got := notes.Match(tags, version.Version{Major: 2}); got != "" {
+		t.Errorf("Match(2.0.0) = %q, want none", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var out bytes.Buffer
+	notes.Write(&out, "fork/v1.2.0", version.Version{Major: 1, Minor: 2}, &notes.Release{Tag: "v1.2.0", URL: "https://github.com/owner/ext/releases/tag/v1.2.0", Body: "- Fixed things\n"})
+	for _, want := range []string{"Fork release fork/v1.2.0 of upstream version 1.2.0.", "From [v1.2.0](https://github.com/owner/ext/releases/tag/v1.2.0):", "- Fixed things"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("notes do not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	notes.Write(&out, "", version.Version{Major: 1}, nil)
+	if strings.Contains(out.String(), "Upstream Release Notes") {
+		t.Errorf("expected no upstream notes:\n%s", out.String())
+	}
+}
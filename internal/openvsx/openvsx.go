@@ -0,0 +1,133 @@
+// Package openvsx is a client for the public API of an OpenVSX registry.
+package openvsx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the public OpenVSX registry.
+const DefaultURL = "https://open-vsx.org"
+
+// ErrNotFound is returned when the registry has no such extension or version.
+var ErrNotFound = errors.New("not found in the registry")
+
+// Client queries a registry.
+type Client struct {
+	// URL is the registry's base URL, e.g. DefaultURL.
+	URL  string
+	HTTP *http.Client
+}
+
+// New returns a client for the registry at baseURL, or DefaultURL when it is empty.
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &Client{URL: strings.TrimSuffix(baseURL, "/"), HTTP: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Extension is a published extension version.
+type Extension struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	// TargetPlatform is the platform the package is for, or "universal".
+	TargetPlatform string `json:"targetPlatform"`
+	// Files links to the version's files, e.g. "download" and "sha256".
+	Files map[string]string `json:"files"`
+	// AllVersions links to every published version, keyed by version, plus "latest".
+	AllVersions map[string]string `json:"allVersions"`
+}
+
+// Extension returns the given version of an extension, or its latest version when
+// version is empty.
+func (c *Client) Extension(namespace, name, version string) (*Extension, error) {
+	return c.ExtensionTarget(namespace, name, "", version)
+}
+
+// ExtensionTarget is like Extension for the package of a target platform, e.g. "linux-x64".
+// An empty target selects the universal package.
+func (c *Client) ExtensionTarget(namespace, name, target, version string) (*Extension, error) {
+	u := fmt.Sprintf("%s/api/%s/%s", c.URL, url.PathEscape(namespace), url.PathEscape(name))
+	if target != "" {
+		u += "/" + url.PathEscape(target)
+	}
+	if version != "" {
+		u += "/" + url.PathEscape(version)
+	}
+
+	resp, err := c.HTTP.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", strings.Join(strings.Fields(fmt.Sprintf("%s.%s %s %s", namespace, name, target, version)), " "), ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	var ext Extension
+	if err := json.NewDecoder(resp.Body).Decode(&ext); err != nil {
+		return nil, fmt.Errorf("GET %s: invalid response: %w", u, err)
+	}
+	return &ext, nil
+}
+
+// SHA256 returns the hex SHA-256 of the extension's package. It reads the checksum the
+// registry publishes alongside the package and, when there is none, hashes the download.
+func (c *Client) SHA256(ext *Extension) (string, error) {
+	if u := ext.Files["sha256"]; u != "" {
+		var sum string
+		err := c.get(u, func(body io.Reader) error {
+			data, err := io.ReadAll(io.LimitReader(body, 1024))
+			if err != nil {
+				return err
+			}
+			fields := strings.Fields(string(data))
+			if len(fields) == 0 {
+				return fmt.Errorf("GET %s: empty checksum", u)
+			}
+			sum = strings.ToLower(fields[0])
+			return nil
+		})
+		return sum, err
+	}
+
+	u := ext.Files["download"]
+	if u == "" {
+		return "", fmt.Errorf("%s.%s %s has no download", ext.Namespace, ext.Name, ext.Version)
+	}
+	h := sha256.New()
+	if err := c.get(u, func(body io.Reader) error {
+		_, err := io.Copy(h, body)
+		return err
+	}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// get requests u and passes the body of a successful response to read.
+func (c *Client) get(u string, read func(io.Reader) error) error {
+	resp, err := c.HTTP.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return read(resp.Body)
+}
@@ -0,0 +1,52 @@
+package openvsx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+)
+
+func TestExtension(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/pub/ext":
+			w.Write([]byte(`{"namespace": "pub", "name": "ext", "version": "1.2.0", "files": {"download": "https://example.com/ext.vsix"}}`))
+		case "/api/pub/ext/linux-x64/1.2.0":
+			w.Write([]byte(`{"namespace": "pub", "name": "ext", "version": "1.2.0", "targetPlatform": "linux-x64"}`))
+		case "/api/pub/ext/1.1.0":
+			w.Write([]byte(`{"namespace": "pub", "name": "ext", "version": "1.1.0"}`))
+		case "/api/pub/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := openvsx.New(srv.URL + "/")
+
+	latest, err := client.Extension("pub", "ext", "")
+	if err != nil {
+		t.Fatalf("Extension failed: %v", err)
+	}
+	if latest.Version != "1.2.0" || latest.Files["download"] != "https://example.com/ext.vsix" {
+		t.Errorf("unexpected latest version %+v", latest)
+	}
+
+	if v, err := client.Extension("pub", "ext", "1.1.0"); err != nil || v.Version != "1.1.0" {
+		t.Errorf("Extension(1.1.0) = %+v, %v", v, err)
+	}
+
+	if v, err := client.ExtensionTarget("pub", "ext", "linux-x64", "1.2.0"); err != nil || v.TargetPlatform != "linux-x64" {
+		t.Errorf("ExtensionTarget(linux-x64) = %+v, %v", v, err)
+	}
+
+	if _, err := client.Extension("pub", "ext", "9.9.9"); !errors.Is(err, openvsx.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := client.Extension("pub", "broken", ""); err == nil || errors.Is(err, openvsx.ErrNotFound) {
+		t.Errorf("expected a server error, got %v", err)
+	}
+}
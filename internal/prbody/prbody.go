@@ -0,0 +1,260 @@
+// Package prbody implements the pr-body command, which describes what a sync pull request brings in.
+//
+// Usage:
+//
+//	ovsx-setup pr-body --base <ref> --head <ref> [--upstream-url <url>]
+//
+// The Markdown body lists the upstream commits being merged grouped by
+// conventional-commit type, the package.json version change, changed dependency
+// ranges, and whether merging will publish a new version to OpenVSX.
+package prbody
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/bump"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+)
+
+// maxCommits caps the listed commits so the body stays within GitHub's size limit.
+const maxCommits = 200
+
+// commitTypes maps conventional-commit types to section titles, in display order.
+var commitTypes = []struct{ Type, Title string }{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"docs", "Documentation"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"test", "Tests"},
+	{"style", "Style"},
+	{"chore", "Chores"},
+	{"revert", "Reverts"},
+}
+
+var conventionalPattern = regexp.MustCompile(`^(\w+)(\([^)]*\))?(!)?: `)
+
+// Summary describes the changes between the base and head of a sync pull request.
+type Summary struct {
+	UpstreamURL  string
+	Commits      []git.Commit
+	From, To     *manifest.Manifest
+	Dependencies []DependencyChange
+	Release      *bump.Result
+	WillRelease  bool
+}
+
+// DependencyChange is a dependency whose version range differs between base and head.
+// From is empty for added dependencies and To is empty for removed ones.
+type DependencyChange struct {
+	Name, Kind, From, To string
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("pr-body", flag.ContinueOnError)
+	base := fs.String("base", "", "The ref the pull request merges into")
+	head := fs.String("head", "HEAD", "The ref containing the merged upstream changes")
+	upstreamURL := fs.String("upstream-url", "", "Upstream repository URL to mention in the body")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *base == "" {
+		return fmt.Errorf("--base is required")
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	s, err := Build(cfg, *base, *head)
+	if err != nil {
+		return err
+	}
+	s.UpstreamURL = *upstreamURL
+	s.WriteMarkdown(os.Stdout)
+	return nil
+}
+
+// Build summarizes the commits and manifest changes that head brings into base.
+func Build(cfg *config.Config, base, head string) (*Summary, error) {
+	args := []string{"--no-merges", head, "--not", base}
+	if cfg.SyncStrategy == config.SyncStrategyPatches {
+		// The rebuilt fork does not contain upstream's history, only the revisions it was built from
+		if to, err := git.Output("show", head+":"+config.UpstreamRevisionPath); err == nil {
+			args = []string{"--no-merges", to}
+			if from, err := git.Output("show", base+":"+config.UpstreamRevisionPath); err == nil {
+				args = append(args, "--not", from)
+			}
+		}
+	}
+	if cfg.Scoped() {
+		// Upstream commits outside the sync paths and shared files were not merged in any meaningful way
+		args = append(append(args, "--"), cfg.ScopePaths()...)
+	}
+	commits, err := git.Log(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestPath := path.Join(tag.CleanPath(cfg.ExtensionDir()), manifest.FileName)
+	from, err := manifestAt(base, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	to, err := manifestAt(head, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := git.Tags()
+	if err != nil {
+		return nil, err
+	}
+	release, err := bump.Next(cfg, to, tags, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Summary{
+		Commits:      commits,
+		From:         from,
+		To:           to,
+		Dependencies: diffDependencies(from, to),
+		Release:      release,
+		WillRelease:  !tags[release.Tag],
+	}, nil
+}
+
+// manifestAt reads the manifest at a ref.
+func manifestAt(ref, file string) (*manifest.Manifest, error) {
+	data, err := git.Output("show", ref+":"+file)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Parse([]byte(data))
+}
+
+func diffDependencies(from, to *manifest.Manifest) []DependencyChange {
+	var changes []DependencyChange
+	for _, kind := range []struct {
+		name     string
+		from, to map[string]string
+	}{
+		{"dependencies", from.Dependencies, to.Dependencies},
+		{"devDependencies", from.DevDependencies, to.DevDependencies},
+		{"peerDependencies", from.PeerDependencies, to.PeerDependencies},
+		{"optionalDependencies", from.OptionalDependencies, to.OptionalDependencies},
+	} {
+		names := map[string]bool{}
+		for name := range kind.from {
+			names[name] = true
+		}
+		for name := range kind.to {
+			names[name] = true
+		}
+
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			if kind.from[name] != kind.to[name] {
+				changes = append(changes, DependencyChange{Name: name, Kind: kind.name, From: kind.from[name], To: kind.to[name]})
+			}
+		}
+	}
+	return changes
+}
+
+// WriteMarkdown writes the pull request body.
+func (s *Summary) WriteMarkdown(w io.Writer) {
+	if s.UpstreamURL != "" {
+		fmt.Fprintf(w, "Automated sync from %s.\n\n", s.UpstreamURL)
+	}
+
+	fmt.Fprintln(w, "## Release")
+	fmt.Fprintln(w)
+	if s.From.Version == s.To.Version {
+		fmt.Fprintf(w, "Version: `%s` (unchanged)\n\n", s.To.Version)
+	} else {
+		fmt.Fprintf(w, "Version: `%s` → `%s`\n\n", s.From.Version, s.To.Version)
+	}
+	if s.WillRelease {
+		fmt.Fprintf(w, "Merging will publish **%s** to OpenVSX (tag `%s`).\n", s.Release.Version, s.Release.Tag)
+	} else {
+		fmt.Fprintf(w, "Merging will **not** trigger an OpenVSX release: tag `%s` already exists.\n", s.Release.Tag)
+	}
+
+	fmt.Fprintf(w, "\n## Upstream Commits (%d)\n", len(s.Commits))
+	commits := s.Commits
+	if len(commits) > maxCommits {
+		commits = commits[:maxCommits]
+	}
+	groups := map[string][]git.Commit{}
+	for _, c := range commits {
+		groups[commitType(c.Subject)] = append(groups[commitType(c.Subject)], c)
+	}
+	for _, t := range append(commitTypes, struct{ Type, Title string }{"", "Other"}) {
+		if len(groups[t.Type]) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n### %s\n\n", t.Title)
+		for _, c := range groups[t.Type] {
+			fmt.Fprintf(w, "- %s %s\n", c.Hash, c.Subject)
+		}
+	}
+	if len(s.Commits) > maxCommits {
+		fmt.Fprintf(w, "\n_…and %d more._\n", len(s.Commits)-maxCommits)
+	}
+
+	if len(s.Dependencies) > 0 {
+		fmt.Fprintln(w, "\n## Dependency Changes")
+		fmt.Fprintln(w, "\n| Package | Type | From | To |")
+		fmt.Fprintln(w, "| :------ | :--- | :--- | :- |")
+		for _, d := range s.Dependencies {
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", d.Name, d.Kind, orDash(d.From), orDash(d.To))
+		}
+	}
+}
+
+// commitType returns the conventional-commit type of a subject, or "" when it has none
+// or the type is not a known one.
+func commitType(subject string) string {
+	m := conventionalPattern.FindStringSubmatch(subject)
+	if m == nil {
+		return ""
+	}
+	t := strings.ToLower(m[1])
+	for _, known := range commitTypes {
+		if known.Type == t {
+			return t
+		}
+	}
+	return ""
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return "`" + s + "`"
+}
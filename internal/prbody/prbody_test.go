@@ -0,0 +1,67 @@
+package prbody_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gittest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/prbody"
+)
+
+func TestBuild(t *testing.T) {
+	gittest.Identity(t)
+	t.Chdir(t.TempDir())
+
+	gittest.Run(t, ".", "init", "-q", "-b", "main")
+	gittest.CommitFile(t, ".", "package.json", `{"name": "ext", "version": "1.0.0", "dependencies": {"a": "^1.0.0", "b": "^2.0.0"}}`, "initial")
+	gittest.Run(t, ".", "checkout", "-q", "-b", "upstream-sync")
+	gittest.CommitFile(t, ".", "src.ts", "1", "feat(core): add thing")
+	gittest.CommitFile(t, ".", "src.ts", "2", "fix: broken thing")
+	gittest.CommitFile(t, ".", "notes.txt", "3", "update notes")
+	gittest.CommitFile(t, ".", "package.json", `{"name": "ext", "version": "1.1.0", "dependencies": {"a": "^1.2.0", "c": "^3.0.0"}}`, "chore: release 1.1.0")
+
+	cfg := &config.Config{}
+	s, err := prbody.Build(cfg, "main", "upstream-sync")
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(s.Commits) != 4 || !s.WillRelease || s.Release.Tag != "v1.1.0" {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if len(s.Dependencies) != 3 {
+		t.Errorf("unexpected dependency changes %+v", s.Dependencies)
+	}
+
+	s.UpstreamURL = "https://github.com/owner/ext"
+	var md bytes.Buffer
+	s.WriteMarkdown(&md)
+	for _, want := range []string{
+		"Automated sync from https://github.com/owner/ext.",
+		"Version: `1.0.0` → `1.1.0`",
+		"Merging will publish **1.1.0** to OpenVSX (tag `v1.1.0`).",
+		"## Upstream Commits (4)",
+		"### Features\n\n- ",
+		"### Bug Fixes\n\n- ",
+		"### Chores\n\n- ",
+		"### Other\n\n- ",
+		"| `a` | dependencies | `^1.0.0` | `^1.2.0` |",
+		"| `b` | dependencies | `^2.0.0` | — |",
+		"| `c` | dependencies | — | `^3.0.0` |",
+	} {
+		if !bytes.Contains(md.Bytes(), []byte(want)) {
+			t.Errorf("body does not contain %q:\n%s", want, md.String())
+		}
+	}
+
+	gittest.Run(t, ".", "tag", "v1.1.0")
+	if s, err = prbody.Build(cfg, "main", "upstream-sync"); err != nil || s.WillRelease {
+		t.Errorf("expected no release once tagged, got %+v, %v", s, err)
+	}
+
+	md.Reset()
+	s.WriteMarkdown(&md)
+	if !bytes.Contains(md.Bytes(), []byte("will **not** trigger an OpenVSX release: tag `v1.1.0` already exists")) {
+		t.Errorf("unexpected body:\n%s", md.String())
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
 	"github.com/timsexperiments/ovsx-fork-tools/internal/setup/workflows"
 )
 
@@ -30,6 +32,37 @@ func Run() error {
 
 	var publisherFlag string
 	var extensionPathFlag string
+	var tagTemplateFlag string
+	var versionSchemeFlag string
+	var revisionDigitsFlag int
+	var syncModeFlag string
+	var upstreamTagPatternFlag string
+	var upstreamURLFlag string
+	var upstreamBranchFlag string
+	var conflictPolicies []config.ConflictPolicy
+	var autoMergeFlag *bool
+	var mergeMethodFlag string
+	var tokenSecretFlag string
+	var appIDFlag string
+	var dispatchReleaseFlag *bool
+	var syncStrategyFlag string
+	var syncPaths []string
+	var sharedFiles []string
+	var buildScopeFlag string
+	var preBuildCommandFlag string
+	var buildCommandFlag string
+	var packageArgsFlag string
+	var nodeVersionFlag string
+	var syncScheduleFlag string
+	var syncBranchFlag string
+	var baseBranchFlag string
+	var releaseBranches []string
+	var registries []config.Registry
+	var marketplaceFlag *bool
+	var targetsFlag string
+	var preReleaseBranches []string
+	var preReleaseRuleFlag string
+	var sbomFlag string
 	flag.StringVar(&publisherFlag, "p", "", "OpenVSX Publisher ID")
 	flag.StringVar(&publisherFlag, "publisher", "", "OpenVSX Publisher ID")
 	flag.StringVar(&publisherFlag, "ovsx-publisher", "", "OpenVSX Publisher ID")
@@ -37,19 +70,256 @@ func Run() error {
 	flag.StringVar(&extensionPathFlag, "extension-path", "", "Extension Path")
 	flag.StringVar(&extensionPathFlag, "path", "", "Extension Path")
 	flag.StringVar(&extensionPathFlag, "dir", "", "Extension Path")
+	flag.StringVar(&tagTemplateFlag, "tag-template", "", "Release tag template, e.g. '{name}@{version}'")
+	flag.StringVar(&versionSchemeFlag, "version-scheme", "", "Fork version scheme: 'upstream' or 'revision'")
+	flag.IntVar(&revisionDigitsFlag, "revision-digits", 0, "Patch digits reserved for fork revisions (revision scheme)")
+	flag.StringVar(&syncModeFlag, "sync-mode", "", "Upstream sync mode: 'branch' or 'tag'")
+	flag.StringVar(&upstreamTagPatternFlag, "upstream-tag-pattern", "", "Upstream release tag pattern for the tag sync mode (default 'v*')")
+	flag.StringVar(&upstreamURLFlag, "upstream", "", "Upstream repository URL (default: the GitHub fork parent)")
+	flag.StringVar(&upstreamBranchFlag, "upstream-branch", "", "Upstream branch to sync (default: upstream's default branch)")
+	flag.Func("conflict-policy", "Sync conflict policy '<glob>=<policy>[:fields]' (repeatable)", func(s string) error {
+		p, err := config.ParseConflictPolicy(s)
+		if err != nil {
+			return err
+		}
+		conflictPolicies = append(conflictPolicies, p)
+		return nil
+	})
+	flag.BoolFunc("auto-merge", "Enable auto-merge on sync pull requests (--auto-merge=false to disable)", func(s string) error {
+		v, err := strconv.ParseBool(s)
+		autoMergeFlag = &v
+		return err
+	})
+	flag.StringVar(&mergeMethodFlag, "merge-method", "", "Merge method for auto-merged sync pull requests: 'merge', 'squash' or 'rebase'")
+	flag.StringVar(&tokenSecretFlag, "token-secret", "", "Secret holding a personal access token for the sync workflow (default 'SYNC_TOKEN')")
+	flag.StringVar(&appIDFlag, "app-id", "", "GitHub App ID for the sync workflow; its private key goes in the 'SYNC_APP_PRIVATE_KEY' secret")
+	flag.BoolFunc("dispatch-release", "Dispatch the release workflow once an auto-merged sync pull request is merged", func(s string) error {
+		v, err := strconv.ParseBool(s)
+		dispatchReleaseFlag = &v
+		return err
+	})
+	flag.StringVar(&syncStrategyFlag, "sync-strategy", "", "How upstream is synced: 'merge' or 'patches'")
+	flag.Func("sync-path", "Only sync upstream changes in this directory or file (repeatable)", func(s string) error {
+		syncPaths = append(syncPaths, s)
+		return nil
+	})
+	flag.Func("shared-file", "Root file merged along with the sync paths (repeatable, default: package.json and lockfiles)", func(s string) error {
+		sharedFiles = append(sharedFiles, s)
+		return nil
+	})
+	flag.StringVar(&buildScopeFlag, "build-scope", "", "Release build scope: 'all' or 'extension'")
+	flag.StringVar(&preBuildCommandFlag, "pre-build-command", "", "Command run from the repository root before the release build")
+	flag.StringVar(&buildCommandFlag, "build-command", "", "Command run from the repository root instead of the default release build")
+	flag.StringVar(&packageArgsFlag, "package-args", "", "Extra 'vsce package' arguments, e.g. '--no-dependencies'")
+	flag.StringVar(&nodeVersionFlag, "node-version", "", "Node.js version the workflows set up (default 'lts/*')")
+	flag.StringVar(&syncScheduleFlag, "sync-schedule", "", "Cron expression (UTC) for the sync workflow (default '0 3 * * *')")
+	flag.StringVar(&syncBranchFlag, "sync-branch", "", "Branch upstream is merged into (default 'upstream-sync')")
+	flag.StringVar(&baseBranchFlag, "base-branch", "", "Fork branch sync pull requests target (default: the default branch)")
+	flag.Func("release-branch", "Branch or pattern that releases on push (repeatable, default: the base branch, or main and master)", func(s string) error {
+		releaseBranches = append(releaseBranches, s)
+		return nil
+	})
+	flag.Func("registry", "OpenVSX registry to publish to, '<url>=<token secret>' (repeatable, default 'https://open-vsx.org=OPEN_VSX_TOKEN')", func(s string) error {
+		r, err := config.ParseRegistry(s)
+		if err != nil {
+			return err
+		}
+		registries = append(registries, r)
+		return nil
+	})
+	flag.BoolFunc("marketplace", "Also publish releases to the VS Marketplace with the 'VSCE_PAT' secret", func(s string) error {
+		v, err := strconv.ParseBool(s)
+		marketplaceFlag = &v
+		return err
+	})
+	flag.StringVar(&targetsFlag, "targets", "", "Comma-separated target platforms to package separately, e.g. 'linux-x64,darwin-arm64' (a universal package is always built)")
+	flag.Func("pre-release-branch", "Branch or pattern whose releases are published as pre-releases (repeatable)", func(s string) error {
+		preReleaseBranches = append(preReleaseBranches, s)
+		return nil
+	})
+	flag.StringVar(&preReleaseRuleFlag, "pre-release-rule", "", "Publish versions matching this rule as pre-releases: 'odd-minor'")
+	flag.StringVar(&sbomFlag, "sbom", "", "Generate an SBOM ('cyclonedx' or 'spdx') and a provenance statement with each release")
 	flag.Parse()
 
-	publisherName := publisherFlag
-	extensionPath := extensionPathFlag
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+
+	if publisherFlag != "" {
+		cfg.Publisher = publisherFlag
+		fmt.Printf("Using Publisher ID from flag: %s\n", cfg.Publisher)
+	}
+
+	if extensionPathFlag != "" {
+		cfg.ExtensionPath = extensionPathFlag
+		fmt.Printf("Using Extension Path from flag: %s\n", cfg.ExtensionPath)
+	}
+
+	if tagTemplateFlag != "" {
+		cfg.TagTemplate = tagTemplateFlag
+		fmt.Printf("Using Tag Template from flag: %s\n", cfg.TagTemplate)
+	}
+
+	if versionSchemeFlag != "" {
+		cfg.VersionScheme = versionSchemeFlag
+		fmt.Printf("Using Version Scheme from flag: %s\n", cfg.VersionScheme)
+	}
+
+	if revisionDigitsFlag != 0 {
+		cfg.RevisionDigits = revisionDigitsFlag
+	}
+
+	if syncModeFlag != "" {
+		cfg.SyncMode = syncModeFlag
+		fmt.Printf("Using Sync Mode from flag: %s\n", cfg.SyncMode)
+	}
+
+	if upstreamTagPatternFlag != "" {
+		cfg.UpstreamTagPattern = upstreamTagPatternFlag
+	}
+
+	if upstreamURLFlag != "" {
+		cfg.UpstreamURL = upstreamURLFlag
+		fmt.Printf("Using Upstream from flag: %s\n", cfg.UpstreamURL)
+	}
+
+	if upstreamBranchFlag != "" {
+		cfg.UpstreamBranch = upstreamBranchFlag
+		fmt.Printf("Using Upstream Branch from flag: %s\n", cfg.UpstreamBranch)
+	}
+
+	if len(conflictPolicies) > 0 {
+		cfg.ConflictPolicies = conflictPolicies
+		fmt.Printf("Using %d Conflict Policies from flags\n", len(cfg.ConflictPolicies))
+	}
+
+	if autoMergeFlag != nil {
+		cfg.AutoMerge = autoMergeFlag
+		fmt.Printf("Using Auto-Merge from flag: %t\n", *cfg.AutoMerge)
+	}
+
+	if mergeMethodFlag != "" {
+		cfg.MergeMethod = mergeMethodFlag
+		fmt.Printf("Using Merge Method from flag: %s\n", cfg.MergeMethod)
+	}
+
+	if tokenSecretFlag != "" {
+		cfg.TokenSecret = tokenSecretFlag
+		fmt.Printf("Using Token Secret from flag: %s\n", cfg.TokenSecret)
+	}
+
+	if appIDFlag != "" {
+		cfg.AppID = appIDFlag
+		fmt.Printf("Using GitHub App ID from flag: %s\n", cfg.AppID)
+	}
+
+	if dispatchReleaseFlag != nil {
+		cfg.DispatchRelease = dispatchReleaseFlag
+		fmt.Printf("Using Dispatch Release from flag: %t\n", *cfg.DispatchRelease)
+	}
+
+	if syncStrategyFlag != "" {
+		cfg.SyncStrategy = syncStrategyFlag
+		fmt.Printf("Using Sync Strategy from flag: %s\n", cfg.SyncStrategy)
+	}
+
+	if len(syncPaths) > 0 {
+		cfg.SyncPaths = syncPaths
+		fmt.Printf("Using Sync Paths from flags: %s\n", strings.Join(cfg.SyncPaths, ", "))
+	}
+
+	if len(sharedFiles) > 0 {
+		cfg.SharedFiles = sharedFiles
+		fmt.Printf("Using Shared Files from flags: %s\n", strings.Join(cfg.SharedFiles, ", "))
+	}
+
+	if buildScopeFlag != "" {
+		cfg.BuildScope = buildScopeFlag
+		fmt.Printf("Using Build Scope from flag: %s\n", cfg.BuildScope)
+	}
+
+	if preBuildCommandFlag != "" {
+		cfg.PreBuildCommand = preBuildCommandFlag
+		fmt.Printf("Using Pre-Build Command from flag: %s\n", cfg.PreBuildCommand)
+	}
+
+	if buildCommandFlag != "" {
+		cfg.BuildCommand = buildCommandFlag
+		fmt.Printf("Using Build Command from flag: %s\n", cfg.BuildCommand)
+	}
+
+	if packageArgsFlag != "" {
+		cfg.PackageArgs = packageArgsFlag
+		fmt.Printf("Using Package Arguments from flag: %s\n", cfg.PackageArgs)
+	}
+
+	if nodeVersionFlag != "" {
+		cfg.NodeVersion = nodeVersionFlag
+		fmt.Printf("Using Node Version from flag: %s\n", cfg.NodeVersion)
+	}
+
+	if syncScheduleFlag != "" {
+		cfg.SyncSchedule = syncScheduleFlag
+		fmt.Printf("Using Sync Schedule from flag: %s\n", cfg.SyncSchedule)
+	}
+
+	if syncBranchFlag != "" {
+		cfg.SyncBranch = syncBranchFlag
+		fmt.Printf("Using Sync Branch from flag: %s\n", cfg.SyncBranch)
+	}
+
+	if baseBranchFlag != "" {
+		cfg.BaseBranch = baseBranchFlag
+		fmt.Printf("Using Base Branch from flag: %s\n", cfg.BaseBranch)
+	}
+
+	if len(releaseBranches) > 0 {
+		cfg.ReleaseBranches = releaseBranches
+		fmt.Printf("Using Release Branches from flags: %s\n", strings.Join(cfg.ReleaseBranches, ", "))
+	}
+
+	if len(registries) > 0 {
+		cfg.Registries = registries
+		urls := make([]string, len(registries))
+		for i, r := range registries {
+			urls[i] = r.URL
+		}
+		fmt.Printf("Using Registries from flags: %s\n", strings.Join(urls, ", "))
+	}
+
+	if marketplaceFlag != nil {
+		cfg.Marketplace = marketplaceFlag
+		fmt.Printf("Using Marketplace from flag: %t\n", *cfg.Marketplace)
+	}
 
-	if publisherName != "" {
-		fmt.Printf("Using Publisher ID from flag: %s\n", publisherName)
+	if targetsFlag != "" {
+		cfg.Targets = config.ParseTargets(targetsFlag)
+		fmt.Printf("Using Targets from flag: %s\n", strings.Join(cfg.Targets, ", "))
 	}
 
-	if extensionPath != "" {
-		fmt.Printf("Using Extension Path from flag: %s\n", extensionPath)
+	if len(preReleaseBranches) > 0 {
+		cfg.PreReleaseBranches = preReleaseBranches
+		fmt.Printf("Using Pre-Release Branches from flags: %s\n", strings.Join(cfg.PreReleaseBranches, ", "))
+	}
+
+	if preReleaseRuleFlag != "" {
+		cfg.PreReleaseRule = preReleaseRuleFlag
+		fmt.Printf("Using Pre-Release Rule from flag: %s\n", cfg.PreReleaseRule)
+	}
+
+	if sbomFlag != "" {
+		cfg.SBOMFormat = sbomFlag
+		fmt.Printf("Using SBOM Format from flag: %s\n", cfg.SBOMFormat)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
 
+	publisherName := cfg.Publisher
+	extensionPath := cfg.ExtensionPath
+
 	fmt.Println("\n--- Installing Workflows ---")
 	workflowDir := filepath.Join(".github", "workflows")
 	if err := os.MkdirAll(workflowDir, 0755); err != nil {
@@ -64,13 +334,7 @@ func Run() error {
 	}
 
 	for filename, content := range filesToInstall {
-		fileContent := string(content)
-		if publisherName != "" {
-			fileContent = strings.ReplaceAll(fileContent, `${{ vars.PUBLISHER_NAME }}`, publisherName)
-		}
-		if extensionPath != "" {
-			fileContent = strings.ReplaceAll(fileContent, `${{ vars.EXTENSION_PATH }}`, extensionPath)
-		}
+		fileContent := render(content, cfg)
 
 		destPath := filepath.Join(workflowDir, filename)
 		if err := os.WriteFile(destPath, []byte(fileContent), 0644); err != nil {
@@ -85,13 +349,42 @@ func Run() error {
 	}
 
 	fmt.Println("✅ Workflow files created in .github/workflows/")
+
+	if cfg.AutoMerge != nil && *cfg.AutoMerge {
+		fmt.Println("\n--- Checking Auto-Merge Settings ---")
+		for _, warning := range checkAutoMerge(cfg) {
+			fmt.Printf("⚠️  %s\n", warning)
+		}
+	}
+
+	if err := cfg.Save("."); err != nil {
+		return fmt.Errorf("error writing %s: %w", config.Path, err)
+	}
+	if err := exec.Command("git", "add", config.Path).Run(); err != nil {
+		return fmt.Errorf("failed to git add %s: %w", config.Path, err)
+	}
+	fmt.Printf("Saved configuration to %s\n", config.Path)
 	fmt.Println("\n==========================================")
 	fmt.Println("   Setup Complete!                        ")
 	fmt.Println("==========================================")
 	fmt.Println("Next Steps:")
 	step := 1
-	fmt.Printf("%d. Ensure 'OPEN_VSX_TOKEN' is set in your repository secrets.\n", step)
-	step++
+	for _, r := range cfg.PublishTargets() {
+		fmt.Printf("%d. Ensure '%s' is set in your repository secrets (token for %s).\n", step, r.TokenSecret, r.URL)
+		step++
+	}
+	if cfg.Marketplace != nil && *cfg.Marketplace {
+		fmt.Printf("%d. Ensure '%s' is set in your repository secrets (VS Marketplace personal access token).\n", step, config.MarketplaceTokenSecret)
+		step++
+	}
+
+	if cfg.AppID != "" {
+		fmt.Printf("%d. Install the GitHub App on this repository and set '%s' in your repository secrets.\n", step, config.AppKeySecret)
+		step++
+	} else if cfg.TokenSecret != "" {
+		fmt.Printf("%d. Set '%s' in your repository secrets to a token with contents, pull requests and workflows write access.\n", step, cfg.TokenSecret)
+		step++
+	}
 
 	if publisherName == "" {
 		fmt.Printf("%d. Set 'PUBLISHER_NAME' in your repository variables (or use -p flag next time).\n", step)
@@ -109,3 +402,118 @@ func Run() error {
 
 	return nil
 }
+
+// render substitutes the configured values into a workflow template.
+// Values that are not configured are left as repository variables so they can be set later.
+func render(content []byte, cfg *config.Config) string {
+	var autoMerge, dispatchRelease, marketplace string
+	if cfg.AutoMerge != nil {
+		autoMerge = strconv.FormatBool(*cfg.AutoMerge)
+	}
+	if cfg.DispatchRelease != nil {
+		dispatchRelease = strconv.FormatBool(*cfg.DispatchRelease)
+	}
+	if cfg.Marketplace != nil {
+		marketplace = strconv.FormatBool(*cfg.Marketplace)
+	}
+
+	fileContent := string(content)
+	for placeholder, value := range map[string]string{
+		`${{ vars.PUBLISHER_NAME }}`:                 cfg.Publisher,
+		`${{ vars.EXTENSION_PATH }}`:                 cfg.ExtensionPath,
+		`${{ vars.TAG_TEMPLATE }}`:                   cfg.TagTemplate,
+		`${{ vars.VERSION_SCHEME }}`:                 cfg.VersionScheme,
+		`${{ vars.SYNC_MODE }}`:                      cfg.SyncMode,
+		`${{ vars.SYNC_STRATEGY }}`:                  cfg.SyncStrategy,
+		`${{ vars.UPSTREAM_TAG_PATTERN }}`:           cfg.UpstreamTagPattern,
+		`${{ vars.UPSTREAM_URL }}`:                   cfg.UpstreamURL,
+		`${{ vars.UPSTREAM_BRANCH }}`:                cfg.UpstreamBranch,
+		`${{ vars.AUTO_MERGE }}`:                     autoMerge,
+		`${{ vars.MERGE_METHOD }}`:                   cfg.MergeMethod,
+		`${{ vars.BUILD_SCOPE }}`:                    cfg.BuildScope,
+		`${{ vars.SYNC_APP_ID }}`:                    cfg.AppID,
+		`${{ vars.DISPATCH_RELEASE }}`:               dispatchRelease,
+		`${{ vars.MARKETPLACE }}`:                    marketplace,
+		`${{ vars.PRE_RELEASE_RULE }}`:               cfg.PreReleaseRule,
+		`${{ vars.SBOM_FORMAT }}`:                    cfg.SBOMFormat,
+		`${{ vars.NODE_VERSION }}`:                   cfg.NodeVersion,
+		`${{ vars.PRE_BUILD_COMMAND }}`:              quote(cfg.PreBuildCommand),
+		`${{ vars.BUILD_COMMAND }}`:                  quote(cfg.BuildCommand),
+		`${{ vars.VSCE_PACKAGE_ARGS }}`:              quote(cfg.PackageArgs),
+		`${{ vars.SYNC_BRANCH || 'upstream-sync' }}`: cfg.SyncBranch,
+		`${{ vars.BASE_BRANCH || github.ref_name }}`: cfg.BaseBranch,
+		`cron: "` + config.DefaultSyncSchedule + `"`: cronLine(cfg.SyncSchedule),
+	} {
+		if value != "" {
+			fileContent = strings.ReplaceAll(fileContent, placeholder, value)
+		}
+	}
+	if cfg.TokenSecret != "" {
+		fileContent = strings.ReplaceAll(fileContent, "secrets."+config.DefaultTokenSecret, "secrets."+cfg.TokenSecret)
+	}
+	if branches := cfg.Releases(); len(branches) > 0 {
+		var list strings.Builder
+		list.WriteString("    branches:\n")
+		for _, b := range branches {
+			fmt.Fprintf(&list, "      - %q\n", b)
+		}
+		fileContent = strings.ReplaceAll(fileContent, "    branches:\n      - main\n      - master\n", list.String())
+	}
+	if len(cfg.Targets) > 0 {
+		var list strings.Builder
+		list.WriteString("        target:\n")
+		for _, t := range cfg.Targets {
+			fmt.Fprintf(&list, "          - %s\n", t)
+		}
+		list.WriteString("          - " + config.TargetUniversal + "\n")
+		fileContent = strings.Replace(fileContent, "        target:\n          - "+config.TargetUniversal+"\n", list.String(), 1)
+	}
+	if len(cfg.Registries) > 0 {
+		var steps, results strings.Builder
+		for i, r := range cfg.Registries {
+			steps.WriteString(publishStep(i, r))
+			results.WriteString(publishResult(i, r))
+		}
+		fileContent = strings.Replace(fileContent, publishStep(0, config.DefaultRegistry), steps.String(), 1)
+		fileContent = strings.Replace(fileContent, publishResult(0, config.DefaultRegistry), results.String(), 1)
+	}
+	return fileContent
+}
+
+// quote renders s as a double-quoted YAML scalar, so commands keep characters such as ": " and " #".
+func quote(s string) string {
+	if s == "" {
+		return ""
+	}
+	return strconv.Quote(s)
+}
+
+// publishStep renders the release workflow step publishing to and verifying registry i.
+func publishStep(i int, r config.Registry) string {
+	return fmt.Sprintf(`      - name: Publish to %[2]s
+        id: publish-%[1]d
+        continue-on-error: true
+        env:
+          REGISTRY_URL: %[3]s
+          OVSX_PAT: ${{ secrets.%[4]s }}
+        run: |
+          cd ${{ env.EXTENSION_PATH }}
+          pnpm dlx ovsx publish *.vsix --registryUrl "$REGISTRY_URL" -p "$OVSX_PAT"
+          cd "$GITHUB_WORKSPACE"
+          go run github.com/timsexperiments/ovsx-fork-tools@latest verify --registry "$REGISTRY_URL" --target "$TARGET"
+
+`, i, r.Host(), r.URL, r.TokenSecret)
+}
+
+// publishResult renders registry i's line of the publish summary.
+func publishResult(i int, r config.Registry) string {
+	return fmt.Sprintf("            %s ${{ steps.publish-%d.outcome }}\n", r.URL, i)
+}
+
+// cronLine renders the sync schedule, or returns "" to keep the template's default.
+func cronLine(schedule string) string {
+	if schedule == "" {
+		return ""
+	}
+	return fmt.Sprintf("cron: %q", schedule)
+}
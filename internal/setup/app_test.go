@@ -112,6 +112,28 @@ func (ot *OvsxTest) AssertFilesNotStaged() *OvsxTest {
 	})
 }
 
+func (ot *OvsxTest) AssertConfigStaged() *OvsxTest {
+	return ot.Assert(func(t *testing.T, err error) {
+		out, _ := exec.Command("git", "status", "--porcelain").Output()
+		if !strings.Contains(string(out), "A  .ovsx-fork/config.json") {
+			t.Error("Config file was not staged")
+		}
+	})
+}
+
+func (ot *OvsxTest) AssertConfigContent(contains string) *OvsxTest {
+	return ot.Assert(func(t *testing.T, _ error) {
+		content, err := os.ReadFile(filepath.Join(".ovsx-fork", "config.json"))
+		if err != nil {
+			t.Errorf("Failed to read config: %v", err)
+			return
+		}
+		if !strings.Contains(string(content), contains) {
+			t.Errorf("Config does not contain %q:\n%s", contains, content)
+		}
+	})
+}
+
 func (ot *OvsxTest) AssertFileContent(filename, contains string) *OvsxTest {
 	return ot.Assert(func(t *testing.T, _ error) {
 		workflowDir := filepath.Join(".github", "workflows")
@@ -224,7 +246,8 @@ func TestRun(t *testing.T) {
 			WithArgs("ovsx-setup", "-p", "flagpub", "-e", "./flagext").
 			AssertNoError().
 			AssertFilesExist().
-			AssertFilesStaged(),
+			AssertFilesStaged().
+			AssertFileContent("ovsx-fork-tools-check-version.yml", "PUBLISHER_NAME: flagpub"),
 
 		NewOvsxSetupTest("Success with Long Flags", WithEnv("PATH", origPath), WithGitInit()).
 			WithArgs("ovsx-setup", "--ovsx-publisher", "longpub", "--extension-path", "./longext").
@@ -232,6 +255,197 @@ func TestRun(t *testing.T) {
 			AssertFilesExist().
 			AssertFilesStaged(),
 
+		NewOvsxSetupTest("Success with Tag Template", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "-p", "tagpub", "--tag-template", "{name}@{version}").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-release.yml", `TAG_TEMPLATE: "{name}@{version}"`).
+			AssertFileContent("ovsx-fork-tools-check-version.yml", `TAG_TEMPLATE: "{name}@{version}"`).
+			AssertFileContent("ovsx-fork-tools-sync.yml", `TAG_TEMPLATE: "{name}@{version}"`).
+			AssertConfigStaged(),
+
+		NewOvsxSetupTest("Invalid Tag Template", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--tag-template", "release-{publisher}").
+			AssertError("unknown placeholder").
+			AssertFilesNotExist("ovsx-fork-tools-release.yml"),
+
+		NewOvsxSetupTest("Success with Revision Scheme", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--version-scheme", "revision").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-release.yml", "VERSION_SCHEME: revision").
+			AssertFileContent("ovsx-fork-tools-check-version.yml", "VERSION_SCHEME: revision"),
+
+		NewOvsxSetupTest("Invalid Version Scheme", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--version-scheme", "calver").
+			AssertError("unknown version scheme"),
+
+		NewOvsxSetupTest("Success with Tag Sync Mode", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sync-mode", "tag", "--upstream-tag-pattern", "release-*").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "SYNC_MODE: tag").
+			AssertFileContent("ovsx-fork-tools-sync.yml", `UPSTREAM_TAG_PATTERN: "release-*"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "SYNC_MODE: tag").
+			AssertFileContent("ovsx-fork-tools-release.yml", `UPSTREAM_TAG_PATTERN: "release-*"`),
+
+		NewOvsxSetupTest("Invalid Sync Mode", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sync-mode", "rebase").
+			AssertError("unknown sync mode"),
+
+		NewOvsxSetupTest("Success with Upstream", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--upstream", "https://gitlab.com/owner/ext.git", "--upstream-branch", "develop").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "UPSTREAM_URL: https://gitlab.com/owner/ext.git").
+			AssertFileContent("ovsx-fork-tools-sync.yml", "UPSTREAM_BRANCH: develop").
+			AssertFileContent("ovsx-fork-tools-release.yml", "UPSTREAM_URL: https://gitlab.com/owner/ext.git"),
+
+		NewOvsxSetupTest("Invalid Upstream", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--upstream", "ftp://example.com/ext").
+			AssertError("invalid upstream URL"),
+
+		NewOvsxSetupTest("Success with Conflict Policies", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--conflict-policy", "README.md=ours", "--conflict-policy", "package.json=json-field-merge:publisher").
+			AssertNoError().
+			AssertConfigContent(`"path": "README.md"`).
+			AssertConfigContent(`"policy": "json-field-merge"`),
+
+		NewOvsxSetupTest("Success with Auto-Merge", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--auto-merge", "--merge-method", "squash").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "AUTO_MERGE: true").
+			AssertFileContent("ovsx-fork-tools-sync.yml", "MERGE_METHOD: squash"),
+
+		NewOvsxSetupTest("Success with Auto-Merge Disabled", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--auto-merge=false").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "AUTO_MERGE: false"),
+
+		NewOvsxSetupTest("Success with Token Secret", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--token-secret", "RELEASE_PAT").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "secrets.RELEASE_PAT || secrets.GITHUB_TOKEN").
+			AssertConfigContent(`"tokenSecret": "RELEASE_PAT"`),
+
+		NewOvsxSetupTest("Success with GitHub App", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--app-id", "123456").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "SYNC_APP_ID: 123456").
+			AssertFileContent("ovsx-fork-tools-sync.yml", "secrets.SYNC_TOKEN || secrets.GITHUB_TOKEN"),
+
+		NewOvsxSetupTest("Success with Dispatch Release", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--auto-merge", "--dispatch-release").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "DISPATCH_RELEASE: true"),
+
+		NewOvsxSetupTest("Dispatch Release without Auto-Merge", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--dispatch-release").
+			AssertError("requires auto-merge"),
+
+		NewOvsxSetupTest("Invalid Token Secret", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--token-secret", "GITHUB_PAT").
+			AssertError("invalid token secret name"),
+
+		NewOvsxSetupTest("Success with Schedule and Branches", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sync-schedule", "30 6 * * MON-FRI", "--sync-branch", "sync/upstream", "--base-branch", "develop").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", `cron: "30 6 * * MON-FRI"`).
+			AssertFileContent("ovsx-fork-tools-sync.yml", `SYNC_BRANCH: "sync/upstream"`).
+			AssertFileContent("ovsx-fork-tools-sync.yml", `BASE_BRANCH: "develop"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "branches:\n      - \"develop\"\n").
+			AssertFileContent("ovsx-fork-tools-check-version.yml", "branches:\n      - \"develop\"\n"),
+
+		NewOvsxSetupTest("Success with Release Branches", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--release-branch", "main", "--release-branch", "release/*").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-release.yml", "branches:\n      - \"main\"\n      - \"release/*\"\n"),
+
+		NewOvsxSetupTest("Invalid Sync Schedule", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sync-schedule", "0 25 * * *").
+			AssertError("invalid cron expression"),
+
+		NewOvsxSetupTest("Success with Sync Paths", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "-e", "packages/ext", "--sync-path", "packages/ext", "--sync-path", "packages/shared", "--build-scope", "extension").
+			AssertNoError().
+			AssertConfigContent(`"packages/shared"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "BUILD_SCOPE: extension"),
+
+		NewOvsxSetupTest("Invalid Build Scope", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--build-scope", "changed").
+			AssertError("unknown build scope"),
+
+		NewOvsxSetupTest("Success with Patches Strategy", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sync-strategy", "patches").
+			AssertNoError().
+			AssertFileContent("ovsx-fork-tools-sync.yml", "SYNC_STRATEGY: patches"),
+
+		NewOvsxSetupTest("Invalid Sync Strategy", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sync-strategy", "rebase").
+			AssertError("unknown sync strategy"),
+
+		NewOvsxSetupTest("Success with Registries", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--registry", "https://open-vsx.org=OPEN_VSX_TOKEN", "--registry", "https://vsx.example.com/=COMPANY_VSX_TOKEN").
+			AssertNoError().
+			AssertConfigContent(`"url": "https://vsx.example.com"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "- name: Publish to open-vsx.org\n        id: publish-0").
+			AssertFileContent("ovsx-fork-tools-release.yml", "- name: Publish to vsx.example.com\n        id: publish-1").
+			AssertFileContent("ovsx-fork-tools-release.yml", "OVSX_PAT: ${{ secrets.COMPANY_VSX_TOKEN }}").
+			AssertFileContent("ovsx-fork-tools-release.yml", "https://vsx.example.com ${{ steps.publish-1.outcome }}"),
+
+		NewOvsxSetupTest("Success with Marketplace", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--marketplace").
+			AssertNoError().
+			AssertConfigContent(`"marketplace": true`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "MARKETPLACE: true"),
+
+		NewOvsxSetupTest("Success with Targets", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--targets", "linux-x64, darwin-arm64").
+			AssertNoError().
+			AssertConfigContent(`"darwin-arm64"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "        target:\n          - linux-x64\n          - darwin-arm64\n          - universal\n"),
+
+		NewOvsxSetupTest("Invalid Targets", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--targets", "linux-x64,windows").
+			AssertError("unknown target"),
+
+		NewOvsxSetupTest("Success with Pre-Release Channel", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--pre-release-branch", "next", "--pre-release-rule", "odd-minor").
+			AssertNoError().
+			AssertConfigContent(`"preReleaseRule": "odd-minor"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "PRE_RELEASE_RULE: odd-minor").
+			AssertFileContent("ovsx-fork-tools-release.yml", "branches:\n      - \"main\"\n      - \"master\"\n      - \"next\"\n").
+			AssertFileContent("ovsx-fork-tools-check-version.yml", "PRE_RELEASE_RULE: odd-minor"),
+
+		NewOvsxSetupTest("Invalid Pre-Release Rule", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--pre-release-rule", "beta").
+			AssertError("unknown pre-release rule"),
+
+		NewOvsxSetupTest("Success with SBOM", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sbom", "spdx").
+			AssertNoError().
+			AssertConfigContent(`"sbomFormat": "spdx"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "SBOM_FORMAT: spdx").
+			AssertFileContent("ovsx-fork-tools-release.yml", "attest create"),
+
+		NewOvsxSetupTest("Invalid SBOM Format", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--sbom", "syft").
+			AssertError("unknown SBOM format"),
+
+		NewOvsxSetupTest("Success with Build Commands", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--pre-build-command", "pnpm run codegen", "--build-command", `pnpm run "vscode:prepublish"`, "--package-args", "--no-dependencies", "--node-version", "20").
+			AssertNoError().
+			AssertConfigContent(`"buildCommand": "pnpm run \"vscode:prepublish\""`).
+			AssertFileContent("ovsx-fork-tools-release.yml", `PRE_BUILD_COMMAND: "pnpm run codegen"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", `BUILD_COMMAND: "pnpm run \"vscode:prepublish\""`).
+			AssertFileContent("ovsx-fork-tools-release.yml", `VSCE_PACKAGE_ARGS: "--no-dependencies"`).
+			AssertFileContent("ovsx-fork-tools-release.yml", "NODE_VERSION: 20\n").
+			AssertFileContent("ovsx-fork-tools-sync.yml", "NODE_VERSION: 20\n"),
+
+		NewOvsxSetupTest("Reserved Package Argument", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--package-args", "--out=ext.vsix").
+			AssertError("package argument --out"),
+
+		NewOvsxSetupTest("Invalid Merge Method", WithEnv("PATH", origPath), WithGitInit()).
+			WithArgs("ovsx-setup", "--merge-method", "fast-forward").
+			AssertError("unknown merge method"),
+
 		NewOvsxSetupTest("Write Failure", WithEnv("PATH", origPath), WithGitInit(), WithDir(".github", 0555)).
 			WithArgs("ovsx-setup", "-p", "failpub", "-e", "./failext").
 			AssertError("permission denied"),
@@ -0,0 +1,82 @@
+package setup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gh"
+)
+
+// checkAutoMerge verifies that the repository can auto-merge sync pull requests with the
+// configured merge method, and returns a warning for each setting that would prevent it.
+func checkAutoMerge(cfg *config.Config) []string {
+	repo, err := gh.Repo()
+	if err != nil {
+		return []string{fmt.Sprintf("Could not verify auto-merge settings: %v", err)}
+	}
+
+	out, err := gh.Output("api", "repos/"+repo, "--jq", "[.allow_auto_merge, .allow_merge_commit, .allow_squash_merge, .allow_rebase_merge, .default_branch] | @tsv")
+	fields := strings.Split(out, "\t")
+	if err != nil || len(fields) != 5 {
+		return []string{fmt.Sprintf("Could not verify auto-merge settings of %s: %v", repo, err)}
+	}
+	allowed := map[string]bool{
+		"auto":                   fields[0] == "true",
+		config.MergeMethodMerge:  fields[1] == "true",
+		config.MergeMethodSquash: fields[2] == "true",
+		config.MergeMethodRebase: fields[3] == "true",
+	}
+	// Sync pull requests target the base branch, or the default branch when none is configured
+	branch := cfg.BaseBranch
+	if branch == "" {
+		branch = fields[4]
+	}
+
+	var warnings []string
+	if !allowed["auto"] {
+		warnings = append(warnings, fmt.Sprintf("Auto-merge is disabled for %s. Enable it with: gh repo edit --enable-auto-merge", repo))
+	}
+
+	method := cfg.MergeMethod
+	if method == "" {
+		method = config.MergeMethodMerge
+	}
+	if !allowed[method] {
+		flagName := map[string]string{
+			config.MergeMethodMerge:  "--enable-merge-commit",
+			config.MergeMethodSquash: "--enable-squash-merge",
+			config.MergeMethodRebase: "--enable-rebase-merge",
+		}[method]
+		warnings = append(warnings, fmt.Sprintf("The %q merge method is disabled for %s. Enable it with: gh repo edit %s", method, repo, flagName))
+	}
+	if method != config.MergeMethodMerge && cfg.SyncStrategy != config.SyncStrategyPatches {
+		warnings = append(warnings, fmt.Sprintf("Sync pull requests merged with %q drop upstream's commits from the fork's history, so later syncs will conflict with the same changes again.", method))
+	}
+
+	dispatch := cfg.DispatchRelease != nil && *cfg.DispatchRelease
+	if cfg.AppID == "" && !dispatch && !hasSecret(repo, cfg.SyncTokenSecret()) {
+		warnings = append(warnings, fmt.Sprintf("Neither a GitHub App (--app-id) nor the %s secret is configured. Sync pull requests merged with the default GITHUB_TOKEN do not trigger the release workflow; configure one, or use --dispatch-release.", cfg.SyncTokenSecret()))
+	}
+
+	if !hasRequiredChecks(repo, branch) {
+		warnings = append(warnings, fmt.Sprintf("No required status checks protect %s. Auto-merge waits for required checks; without them sync pull requests cannot be auto-merged.", branch))
+	}
+	return warnings
+}
+
+// hasRequiredChecks reports whether a branch protection rule or ruleset requires status checks on branch.
+func hasRequiredChecks(repo, branch string) bool {
+	rules, err := gh.Output("api", fmt.Sprintf("repos/%s/rules/branches/%s", repo, branch), "--jq", `[.[] | select(.type == "required_status_checks")] | length`)
+	if err == nil && rules != "" && rules != "0" {
+		return true
+	}
+	contexts, err := gh.Output("api", fmt.Sprintf("repos/%s/branches/%s/protection/required_status_checks", repo, branch), "--jq", ".checks | length")
+	return err == nil && contexts != "" && contexts != "0"
+}
+
+// hasSecret reports whether the repository has an Actions secret called name.
+func hasSecret(repo, name string) bool {
+	secrets, err := gh.Secrets(repo)
+	return err == nil && secrets[name]
+}
@@ -0,0 +1,305 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+)
+
+// Conflict is a file the merge left unresolved.
+type Conflict struct {
+	Path string `json:"path"`
+	// ForkManaged is set for files the fork intentionally owns, that is files
+	// with a conflict resolution policy.
+	ForkManaged bool `json:"forkManaged"`
+	// Error explains why the file's policy could not resolve the conflict.
+	Error string `json:"error,omitempty"`
+}
+
+// Report describes the outcome of merging upstream into the sync branch.
+type Report struct {
+	Target *Target `json:"target"`
+	Branch string  `json:"branch"`
+	Title  string  `json:"title"`
+	Merged bool    `json:"merged"`
+	// Strategy is the sync strategy used, see config.SyncStrategyMerge.
+	Strategy string `json:"strategy"`
+	// UpToDate is set when the fork already contains the target, so there was nothing to merge.
+	UpToDate bool `json:"upToDate"`
+	// Unchanged is set when the sync branch on origin already merges the target into the
+	// current HEAD; the branch is checked out as is and does not need to be pushed again.
+	Unchanged bool `json:"unchanged"`
+	// Applied and FailedPatches are the patches of the series applied by Rebuild and the ones
+	// that no longer apply.
+	Applied       []string       `json:"applied,omitempty"`
+	FailedPatches []PatchFailure `json:"failedPatches,omitempty"`
+	// Skipped are the files upstream changed outside the sync paths; the fork's versions are kept.
+	Skipped   []string   `json:"skipped,omitempty"`
+	Resolved  []Resolved `json:"resolved,omitempty"`
+	Conflicts []Conflict `json:"conflicts,omitempty"`
+	// ConflictCommits are the upstream commits being merged that touch the conflicting files.
+	ConflictCommits []git.Commit `json:"conflictCommits,omitempty"`
+}
+
+// Merge creates or resets branch at the current HEAD and merges the target into it.
+// Nothing is merged when the target is already part of HEAD, or when the branch on origin
+// already contains both, so that syncs without upstream changes leave the branch untouched.
+// Conflicts in files with a conflict resolution policy are resolved automatically and
+// the merge is committed when nothing else conflicts. Otherwise the working tree is left
+// mid-merge so the remaining conflicts can be resolved by hand, and they are listed in the report.
+func Merge(cfg *config.Config, target *Target, branch string) (*Report, error) {
+	report := &Report{Target: target, Branch: branch, Title: target.Title(), Strategy: config.SyncStrategyMerge}
+
+	// The target is the merge base of HEAD and itself exactly when HEAD already contains it
+	if git.IsAncestor(target.Ref, "HEAD") {
+		report.Merged = true
+		report.UpToDate = true
+		return report, nil
+	}
+
+	remote := "origin/" + branch
+	if git.IsAncestor(target.Ref, remote) && git.IsAncestor("HEAD", remote) {
+		if _, err := git.Output("checkout", "-B", branch, remote); err != nil {
+			return nil, err
+		}
+		report.Merged = true
+		report.Unchanged = true
+		return report, nil
+	}
+
+	if cfg.Scoped() {
+		// Only upstream changes within the sync paths and shared files are worth a sync; an error
+		// means the histories are unrelated, in which case there is no merge base to compare with
+		args := append([]string{"diff", "--name-only", "HEAD..." + target.Ref, "--"}, cfg.ScopePaths()...)
+		if changed, err := git.Lines(args...); err == nil && len(changed) == 0 {
+			report.Merged = true
+			report.UpToDate = true
+			return report, nil
+		}
+	}
+
+	if _, err := git.Output("checkout", "-B", branch); err != nil {
+		return nil, err
+	}
+
+	args := []string{"merge", target.Ref, "--allow-unrelated-histories", "-m", target.Title()}
+	if cfg.Scoped() {
+		args = append(args, "--no-commit", "--no-ff")
+	}
+	_, mergeErr := git.Output(args...)
+	if mergeErr == nil && !cfg.Scoped() {
+		report.Merged = true
+		return report, nil
+	}
+
+	files, err := unmerged()
+	if err != nil {
+		return nil, err
+	}
+	if mergeErr != nil && len(files) == 0 {
+		return nil, mergeErr
+	}
+
+	if cfg.Scoped() {
+		if report.Skipped, err = restoreOutOfScope(cfg, files); err != nil {
+			return nil, err
+		}
+		if files, err = unmerged(); err != nil {
+			return nil, err
+		}
+	}
+
+	failures := resolve(cfg, files, report)
+
+	remaining, err := unmerged()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range remaining {
+		_, managed := cfg.Policy(f)
+		report.Conflicts = append(report.Conflicts, Conflict{Path: f, ForkManaged: managed, Error: failures[f]})
+	}
+	if len(remaining) > 0 {
+		args := append([]string{"MERGE_HEAD", "--not", "HEAD", "--"}, remaining...)
+		if report.ConflictCommits, err = git.Log(args...); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	if _, err := git.Output("commit", "--no-edit"); err != nil {
+		return nil, err
+	}
+	report.Merged = true
+	return report, nil
+}
+
+// restoreOutOfScope reverts the merged changes to files outside the sync paths, including
+// conflicting ones, so the merge records upstream as merged while keeping the fork's files.
+// It returns the reverted files.
+func restoreOutOfScope(cfg *config.Config, conflicting []string) ([]string, error) {
+	changed, err := git.Lines("diff", "--cached", "--name-only", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var skipped []string
+	for _, f := range append(changed, conflicting...) {
+		if seen[f] || cfg.InScope(f) {
+			continue
+		}
+		seen[f] = true
+		skipped = append(skipped, f)
+
+		restore := []string{"checkout", "HEAD", "--", f}
+		if _, err := git.Output("cat-file", "-e", "HEAD:"+f); err != nil {
+			// The fork does not have the file, so upstream added it
+			restore = []string{"rm", "-q", "-f", "--", f}
+		}
+		if _, err := git.Output(restore...); err != nil {
+			return nil, err
+		}
+	}
+	return skipped, nil
+}
+
+// ForkManaged reports whether a repository path is one the fork intentionally owns.
+func ForkManaged(cfg *config.Config, file string) bool {
+	_, ok := cfg.Policy(file)
+	return ok
+}
+
+// unmerged lists the files with unresolved conflicts.
+func unmerged() ([]string, error) {
+	return git.Lines("diff", "--name-only", "--diff-filter=U")
+}
+
+// verb describes how the fork was brought up to date.
+func (r *Report) verb() string {
+	if r.Strategy == config.SyncStrategyPatches {
+		return "Rebuilt the fork from"
+	}
+	return "Merged"
+}
+
+// source describes the merged upstream revision.
+func (r *Report) source() string {
+	if r.Target.Tag != "" {
+		return "upstream tag " + r.Target.Tag
+	}
+	return r.Target.Ref
+}
+
+// WriteText writes a human readable summary of the report.
+func (r *Report) WriteText(w io.Writer) {
+	source := r.source()
+
+	for _, res := range r.Resolved {
+		fmt.Fprintf(w, "Resolved %s using the %s policy.\n", res.Path, res.Policy)
+	}
+
+	switch {
+	case r.UpToDate:
+		fmt.Fprintf(w, "Already up to date with %s; nothing to sync.\n", source)
+		return
+	case r.Unchanged:
+		fmt.Fprintf(w, "origin/%s already merges %s; nothing new to push.\n", r.Branch, source)
+		return
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(w, "Kept the fork's version of %d file(s) outside the sync paths.\n", len(r.Skipped))
+	}
+	if len(r.Applied) > 0 {
+		fmt.Fprintf(w, "Applied %d patch(es).\n", len(r.Applied))
+	}
+	if r.Merged {
+		fmt.Fprintf(w, "%s %s into %s.\n", r.verb(), source, r.Branch)
+		return
+	}
+	if len(r.FailedPatches) > 0 {
+		fmt.Fprintf(w, "Rebuilding from %s: %d patch(es) no longer apply:\n", source, len(r.FailedPatches))
+		r.writePatchFailures(w, false)
+		return
+	}
+
+	fmt.Fprintf(w, "Merging %s into %s has conflicts in %d file(s):\n", source, r.Branch, len(r.Conflicts))
+	for _, c := range r.Conflicts {
+		switch {
+		case c.Error != "":
+			fmt.Fprintf(w, "  %s (fork-managed, policy failed: %s)\n", c.Path, c.Error)
+		case c.ForkManaged:
+			fmt.Fprintf(w, "  %s (fork-managed)\n", c.Path)
+		default:
+			fmt.Fprintf(w, "  %s\n", c.Path)
+		}
+	}
+	fmt.Fprintln(w, "\nResolve the conflicts, then run:")
+	fmt.Fprintln(w, "  git add <files>")
+	fmt.Fprintln(w, "  git commit --no-edit")
+}
+
+// WriteMarkdown writes the report as Markdown, suitable for a job summary or a tracking issue.
+func (r *Report) WriteMarkdown(w io.Writer) {
+	switch {
+	case r.UpToDate:
+		fmt.Fprintf(w, "## Upstream sync: nothing new\n\nThe fork already contains `%s` from %s.\n", r.source(), r.Target.URL)
+		return
+	case r.Unchanged:
+		fmt.Fprintf(w, "## Upstream sync: nothing new\n\n`%s` already merges `%s` from %s; the pull request is unchanged.\n", r.Branch, r.source(), r.Target.URL)
+		return
+	}
+
+	if r.Merged {
+		fmt.Fprintf(w, "## Upstream sync succeeded\n\n%s `%s` from %s into `%s`.\n", r.verb(), r.source(), r.Target.URL, r.Branch)
+		if len(r.Applied) > 0 {
+			fmt.Fprintf(w, "\nApplied %d patch(es): %s.\n", len(r.Applied), strings.Join(r.Applied, ", "))
+		}
+	} else if len(r.FailedPatches) > 0 {
+		fmt.Fprintf(w, "## Upstream sync conflicts\n\nRebuilding the fork from `%s` (%s) failed: these patches no longer apply.\n", r.source(), r.Target.URL)
+		fmt.Fprintln(w, "\n| Patch | Error |")
+		fmt.Fprintln(w, "| :---- | :---- |")
+		r.writePatchFailures(w, true)
+		return
+	} else {
+		fmt.Fprintf(w, "## Upstream sync conflicts\n\nMerging `%s` from %s into `%s` has conflicts that the conflict policies could not resolve.\n", r.source(), r.Target.URL, r.Branch)
+		fmt.Fprintln(w, "\n| File | Fork-managed | Policy error |")
+		fmt.Fprintln(w, "| :--- | :----------- | :----------- |")
+		for _, c := range r.Conflicts {
+			managed := "no"
+			if c.ForkManaged {
+				managed = "yes"
+			}
+			fmt.Fprintf(w, "| `%s` | %s | %s |\n", c.Path, managed, c.Error)
+		}
+
+		if len(r.ConflictCommits) > 0 {
+			fmt.Fprintln(w, "\n### Upstream commits touching these files")
+			fmt.Fprintln(w)
+			for _, c := range r.ConflictCommits {
+				fmt.Fprintf(w, "- %s %s\n", c.Hash, c.Subject)
+			}
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(w, "\n%d upstream file(s) outside the sync paths were left unchanged.\n", len(r.Skipped))
+	}
+
+	if len(r.Resolved) > 0 {
+		fmt.Fprintln(w, "\n### Resolved by policy")
+		fmt.Fprintln(w)
+		for _, res := range r.Resolved {
+			fmt.Fprintf(w, "- `%s` (%s)\n", res.Path, res.Policy)
+		}
+	}
+
+	if !r.Merged {
+		fmt.Fprintln(w, "\n### Resolving")
+		fmt.Fprintln(w, "\nRun the sync on your machine from the fork's default branch, resolve the conflicts and commit, then push the branch and open a pull request:")
+		fmt.Fprintf(w, "\n```bash\ngo run github.com/timsexperiments/ovsx-fork-tools@latest sync --branch %s\ngit push --force-with-lease origin %s\n```\n", r.Branch, r.Branch)
+	}
+}
@@ -0,0 +1,250 @@
+package sync
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/glob"
+)
+
+// toolingWorkflows matches the workflows installed by the setup tool.
+const toolingWorkflows = ".github/workflows/ovsx-fork-tools-*.yml"
+
+// PatchFailure is a patch of the series that no longer applies.
+type PatchFailure struct {
+	Patch string `json:"patch"`
+	Error string `json:"error"`
+}
+
+// Rebuild brings the fork up to date with the patches sync strategy. The fork is rebuilt
+// from the target: the fork's tooling (.ovsx-fork and the installed workflows) is added,
+// then each patch in config.PatchDir is applied with 'git am --3way'. Patches that no
+// longer apply are skipped and listed in the report.
+//
+// The result is committed to branch as a single commit on top of the current HEAD whose
+// tree is the rebuilt fork, so the base branch gets a linear history without merge commits.
+// Before rebuilding, HEAD is checked against its own rebuild from the recorded upstream
+// revision, so that changes missing from the patch series are not silently dropped.
+func Rebuild(cfg *config.Config, target *Target, branch string) (*Report, error) {
+	report := &Report{Target: target, Branch: branch, Title: target.Title(), Strategy: config.SyncStrategyPatches}
+
+	rev, err := git.Output("rev-parse", target.Ref+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	recorded, _ := git.Output("show", "HEAD:"+config.UpstreamRevisionPath)
+	if recorded == rev {
+		report.Merged = true
+		report.UpToDate = true
+		return report, nil
+	}
+
+	orig, err := git.Output("rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	origBranch, err := git.Output("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if origBranch == "HEAD" {
+		origBranch = orig
+	}
+	restore := func() { git.Output("checkout", "-q", "-f", origBranch) }
+
+	patchDir, patches, err := copyPatches()
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(patchDir)
+
+	base := recorded
+	if base == "" {
+		// A fork adopting the strategy was last synced by merging upstream
+		base, _ = git.Output("merge-base", "HEAD", target.Ref)
+	}
+	if base != "" {
+		_, failed, err := replay(base, orig, patches)
+		if err != nil {
+			restore()
+			return nil, err
+		}
+		missing, err := git.Lines("diff", "--name-only", "HEAD", orig, "--", ".", ":(exclude)"+config.UpstreamRevisionPath)
+		restore()
+		if err != nil {
+			return nil, err
+		}
+		if len(failed) > 0 || len(missing) > 0 {
+			return nil, fmt.Errorf("the patch series does not reproduce HEAD from upstream %.12s; add the fork's changes to %s before syncing (differing files: %s)", base, config.PatchDir, strings.Join(append(patchNames(failed), missing...), ", "))
+		}
+	}
+
+	applied, failed, err := replay(rev, orig, patches)
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	report.Applied = applied
+	report.FailedPatches = failed
+	if len(failed) > 0 {
+		// HEAD is left at the partial rebuild so the failing patches can be refreshed on it
+		return report, nil
+	}
+
+	rebuilt, err := git.Output("rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	remote := "origin/" + branch
+	if parent, err := git.Output("rev-parse", remote+"^"); err == nil && parent == orig && sameTree(remote, rebuilt) {
+		if _, err := git.Output("checkout", "-q", "-B", branch, remote); err != nil {
+			return nil, err
+		}
+		report.Merged = true
+		report.Unchanged = true
+		return report, nil
+	}
+
+	if _, err := git.Output("checkout", "-q", "-B", branch, orig); err != nil {
+		return nil, err
+	}
+	if _, err := git.Output("read-tree", "-u", "--reset", rebuilt); err != nil {
+		return nil, err
+	}
+	if _, err := git.Output("commit", "-q", "--allow-empty", "-m", target.Title()); err != nil {
+		return nil, err
+	}
+	report.Merged = true
+	return report, nil
+}
+
+// copyPatches copies the patch series out of the working tree, which the rebuild replaces,
+// and returns the temporary directory and the patch file names in order.
+func copyPatches() (string, []string, error) {
+	dir, err := os.MkdirTemp("", "ovsx-patches-*")
+	if err != nil {
+		return "", nil, err
+	}
+	entries, err := os.ReadDir(filepath.FromSlash(config.PatchDir))
+	if err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	var patches []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".patch") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(filepath.FromSlash(config.PatchDir), e.Name()))
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), data, 0644); err != nil {
+			os.RemoveAll(dir)
+			return "", nil, err
+		}
+		patches = append(patches, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(patches)
+	return dir, patches, nil
+}
+
+// replay checks out onto detached, commits the fork's tooling from orig and applies the patches.
+// It returns the names of the applied patches and the ones that failed.
+func replay(onto, orig string, patches []string) ([]string, []PatchFailure, error) {
+	if _, err := git.Output("checkout", "-q", "-f", "--detach", onto); err != nil {
+		return nil, nil, err
+	}
+
+	files, err := git.Lines("ls-tree", "-r", "--name-only", orig, "--", ".ovsx-fork", ".github/workflows")
+	if err != nil {
+		return nil, nil, err
+	}
+	var tooling []string
+	for _, f := range files {
+		if strings.HasPrefix(f, ".ovsx-fork/") || glob.Match(toolingWorkflows, f) {
+			tooling = append(tooling, f)
+		}
+	}
+	if len(tooling) > 0 {
+		if _, err := git.Output(append([]string{"checkout", orig, "--"}, tooling...)...); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.FromSlash(config.UpstreamRevisionPath)), 0755); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(filepath.FromSlash(config.UpstreamRevisionPath), []byte(onto+"\n"), 0644); err != nil {
+		return nil, nil, err
+	}
+	if _, err := git.Output("add", "--", ".ovsx-fork"); err != nil {
+		return nil, nil, err
+	}
+	if _, err := git.Output("commit", "-q", "--allow-empty", "-m", "chore: add fork tooling"); err != nil {
+		return nil, nil, err
+	}
+
+	var applied []string
+	var failed []PatchFailure
+	for _, p := range patches {
+		name := filepath.Base(p)
+		if _, err := git.Output("am", "-q", "--3way", p); err != nil {
+			reason := "does not apply"
+			if conflicts, _ := unmerged(); len(conflicts) > 0 {
+				reason = "conflicts in " + strings.Join(conflicts, ", ")
+			}
+			git.Output("am", "--abort")
+			failed = append(failed, PatchFailure{Patch: name, Error: reason})
+			continue
+		}
+		applied = append(applied, name)
+	}
+	return applied, failed, nil
+}
+
+// sameTree reports whether two commits have the same tree.
+func sameTree(a, b string) bool {
+	treeA, errA := git.Output("rev-parse", a+"^{tree}")
+	treeB, errB := git.Output("rev-parse", b+"^{tree}")
+	return errA == nil && errB == nil && treeA == treeB
+}
+
+// writePatchFailures lists the patches that no longer apply and how to refresh them.
+func (r *Report) writePatchFailures(w io.Writer, markdown bool) {
+	for _, f := range r.FailedPatches {
+		if markdown {
+			fmt.Fprintf(w, "| `%s` | %s |\n", f.Patch, f.Error)
+		} else {
+			fmt.Fprintf(w, "  %s (%s)\n", f.Patch, f.Error)
+		}
+	}
+
+	steps := fmt.Sprintf("git am --3way %[1]s/<patch>\n# resolve the conflicts, then\ngit am --continue\ngit format-patch -1 -o %[1]s --start-number <number>", config.PatchDir)
+	if markdown {
+		fmt.Fprintln(w, "\n### Refreshing patches")
+		fmt.Fprintln(w, "\nRun the sync on your machine from the fork's default branch. It stops at the partial rebuild; apply each failing patch there, resolve it and export it again, then commit the refreshed patch to the default branch:")
+		fmt.Fprintf(w, "\n```bash\ngo run github.com/timsexperiments/ovsx-fork-tools@latest sync\n%s\n```\n", steps)
+		return
+	}
+	fmt.Fprintln(w, "\nHEAD is at the partial rebuild. Refresh each failing patch, then commit it to the default branch:")
+	for _, line := range strings.Split(steps, "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+func patchNames(failed []PatchFailure) []string {
+	names := make([]string, len(failed))
+	for i, f := range failed {
+		names[i] = config.PatchDir + "/" + f.Patch
+	}
+	return names
+}
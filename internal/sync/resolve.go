@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+)
+
+// Resolved is a conflict resolved automatically by a policy.
+type Resolved struct {
+	Path   string `json:"path"`
+	Policy string `json:"policy"`
+}
+
+// lockfileCommands regenerate a lockfile, keyed by its base name.
+var lockfileCommands = map[string][]string{
+	"pnpm-lock.yaml":    {"pnpm", "install", "--lockfile-only", "--ignore-scripts"},
+	"package-lock.json": {"npm", "install", "--package-lock-only", "--ignore-scripts"},
+	"yarn.lock":         {"yarn", "install", "--ignore-scripts"},
+}
+
+// resolve applies the configured policies to the conflicting files, recording the resolved
+// ones in the report. It returns why a policy failed for the files it could not resolve.
+// Lockfiles are regenerated last, once the manifests they depend on are resolved.
+func resolve(cfg *config.Config, files []string, report *Report) map[string]string {
+	failures := map[string]string{}
+	var lockfiles []string
+
+	for _, f := range files {
+		p, ok := cfg.Policy(f)
+		if !ok {
+			continue
+		}
+		if p.Policy == config.PolicyRegenerateLockfile {
+			lockfiles = append(lockfiles, f)
+			continue
+		}
+
+		var err error
+		switch p.Policy {
+		case config.PolicyOurs:
+			err = takeSide(f, "--ours")
+		case config.PolicyTheirs:
+			err = takeSide(f, "--theirs")
+		case config.PolicyJSONFieldMerge:
+			err = mergeJSONFields(f, p.MergeFields())
+		}
+		if err != nil {
+			failures[f] = err.Error()
+			continue
+		}
+		report.Resolved = append(report.Resolved, Resolved{Path: f, Policy: p.Policy})
+	}
+
+	for _, f := range lockfiles {
+		if err := regenerateLockfile(f); err != nil {
+			failures[f] = err.Error()
+			continue
+		}
+		report.Resolved = append(report.Resolved, Resolved{Path: f, Policy: config.PolicyRegenerateLockfile})
+	}
+	return failures
+}
+
+// takeSide resolves a conflict with one side's version of the file, or removes the
+// file when that side deleted it.
+func takeSide(file, side string) error {
+	if _, err := git.Output("checkout", side, "--", file); err != nil {
+		if _, err := git.Output("rm", "-q", "--", file); err != nil {
+			return err
+		}
+		return nil
+	}
+	_, err := git.Output("add", "--", file)
+	return err
+}
+
+// mergeJSONFields merges the top-level fields of a JSON file: the fork's values of the given
+// fields are kept, and every other field takes whichever side changed it since the merge
+// base. It fails, leaving the conflict for a maintainer, when both sides changed a field.
+func mergeJSONFields(file string, fields []string) error {
+	ours, err := git.Output("show", ":2:"+file)
+	if err != nil {
+		return fmt.Errorf("the fork's version is missing")
+	}
+	theirs, err := git.Output("show", ":3:"+file)
+	if err != nil {
+		return fmt.Errorf("upstream's version is missing")
+	}
+	// Without a merge base (both sides added the file) every differing field conflicts
+	base, err := git.Output("show", ":1:"+file)
+	if err != nil {
+		base = "{}"
+	}
+
+	oursObj, err := manifest.ParseObject([]byte(ours))
+	if err != nil {
+		return err
+	}
+	baseObj, err := manifest.ParseObject([]byte(base))
+	if err != nil {
+		return err
+	}
+	merged, err := manifest.ParseObject([]byte(theirs))
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	seen := map[string]bool{}
+	for _, key := range slices.Concat(merged.Keys, oursObj.Keys, baseObj.Keys) {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		o, t, b := oursObj.Get(key), merged.Get(key), baseObj.Get(key)
+		switch {
+		case slices.Contains(fields, key):
+			if o != nil {
+				merged.Set(key, o)
+			}
+		case sameJSON(o, t), sameJSON(o, b):
+			// Upstream's value is already in place
+		case sameJSON(t, b):
+			if o == nil {
+				merged.Delete(key)
+			} else {
+				merged.Set(key, o)
+			}
+		default:
+			conflicts = append(conflicts, key)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("both sides changed %s", strings.Join(conflicts, ", "))
+	}
+
+	out, err := merged.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.FromSlash(file), out, 0644); err != nil {
+		return err
+	}
+	_, err = git.Output("add", "--", file)
+	return err
+}
+
+// sameJSON reports whether two raw JSON values are equal apart from formatting.
+// A nil value stands for a missing field.
+func sameJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// regenerateLockfile takes upstream's lockfile and regenerates it with the package manager,
+// so it matches the merged manifests.
+func regenerateLockfile(file string) error {
+	args, ok := lockfileCommands[path.Base(file)]
+	if !ok {
+		return fmt.Errorf("unknown lockfile type")
+	}
+	if _, err := git.Output("checkout", "--theirs", "--", file); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = filepath.FromSlash(path.Dir(file))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	_, err := git.Output("add", "--", file)
+	return err
+}
@@ -0,0 +1,108 @@
+// Package sync implements the sync command, which merges upstream into the fork.
+//
+// Usage:
+//
+//	ovsx-setup sync [--branch <name>] [--json] [--markdown <file>]
+//
+// It performs the same steps as the sync workflow: detect the upstream repository,
+// fetch it, create the sync branch from the current HEAD and merge upstream into it.
+// With the patches sync strategy the fork is instead rebuilt from upstream and its patch
+// series, and the patches that no longer apply are reported.
+// When HEAD already contains upstream, or origin's sync branch already merges both,
+// it reports that there is nothing new instead of merging again.
+// When the merge conflicts, it reports the conflicting files and which of them are
+// fork-managed, and leaves the working tree ready for manual resolution.
+package sync
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+)
+
+// DefaultBranch is the branch upstream is merged into when none is configured.
+const DefaultBranch = config.DefaultSyncBranch
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	branch := fs.String("branch", "", "Branch to merge upstream into (default: the configured sync branch, or "+DefaultBranch+")")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	markdown := fs.String("markdown", "", "Also write the report as Markdown to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if *branch == "" {
+		*branch = cfg.SyncBranchName()
+	}
+
+	status, err := git.Output("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return err
+	}
+	if status != "" {
+		return fmt.Errorf("the working tree has uncommitted changes; commit or stash them before syncing")
+	}
+
+	url, err := DetectURL(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Fetching upstream %s\n", url)
+
+	target, err := Fetch(cfg, url)
+	if err != nil {
+		return err
+	}
+
+	update := Merge
+	if cfg.SyncStrategy == config.SyncStrategyPatches {
+		update = Rebuild
+	}
+	report, err := update(cfg, target, *branch)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	if *markdown != "" {
+		f, err := os.Create(*markdown)
+		if err != nil {
+			return err
+		}
+		report.WriteMarkdown(f)
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if len(report.FailedPatches) > 0 {
+		return fmt.Errorf("%d patch(es) no longer apply", len(report.FailedPatches))
+	}
+	if !report.Merged {
+		return fmt.Errorf("merge has %d conflicting file(s)", len(report.Conflicts))
+	}
+	return nil
+}
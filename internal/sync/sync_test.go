@@ -0,0 +1,467 @@
+package sync_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gittest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+)
+
+// newFork creates an upstream repository and a clone of it acting as the fork,
+// and changes into the fork.
+func newFork(t *testing.T) (upstream, fork string) {
+	t.Helper()
+	gittest.Identity(t)
+
+	root := t.TempDir()
+	upstream = filepath.Join(root, "upstream")
+	fork = filepath.Join(root, "fork")
+
+	gittest.Run(t, root, "init", "-q", "-b", "main", upstream)
+	gittest.WriteFile(t, upstream, "package.json", `{"name": "ext", "version": "1.0.0"}`)
+	gittest.WriteFile(t, upstream, "src/extension.ts", "export {}\n")
+	gittest.Commit(t, upstream, "initial")
+
+	gittest.Run(t, root, "clone", "-q", upstream, fork)
+	gittest.Run(t, fork, "remote", "remove", "origin")
+	t.Chdir(fork)
+	return upstream, fork
+}
+
+func TestMergeClean(t *testing.T) {
+	upstream, fork := newFork(t)
+	gittest.WriteFile(t, upstream, "NEW.md", "new\n")
+	gittest.Commit(t, upstream, "feat: add file")
+
+	cfg := &config.Config{UpstreamURL: upstream}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if target.Branch != "main" || target.Ref != "upstream/main" {
+		t.Errorf("unexpected target %+v", target)
+	}
+
+	report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !report.Merged || len(report.Conflicts) != 0 {
+		t.Errorf("expected clean merge, got %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(fork, "NEW.md")); err != nil {
+		t.Errorf("upstream file was not merged: %v", err)
+	}
+}
+
+func TestMergeUpToDate(t *testing.T) {
+	upstream, _ := newFork(t)
+
+	cfg := &config.Config{UpstreamURL: upstream}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !report.UpToDate || !report.Merged {
+		t.Errorf("expected an up to date report, got %+v", report)
+	}
+	if branch := gittest.Output(t, ".", "branch", "--show-current"); branch != "main" {
+		t.Errorf("expected to stay on main, got %q", branch)
+	}
+}
+
+func TestMergeUnchanged(t *testing.T) {
+	upstream, _ := newFork(t)
+	gittest.WriteFile(t, upstream, "NEW.md", "new\n")
+	gittest.Commit(t, upstream, "feat: add file")
+
+	cfg := &config.Config{UpstreamURL: upstream}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if _, err := sync.Merge(cfg, target, sync.DefaultBranch); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	// Pretend the merged branch was pushed by an earlier sync
+	pushed := gittest.Output(t, ".", "rev-parse", sync.DefaultBranch)
+	gittest.Run(t, ".", "update-ref", "refs/remotes/origin/"+sync.DefaultBranch, pushed)
+	gittest.Run(t, ".", "checkout", "-q", "main")
+
+	report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !report.Unchanged || report.UpToDate {
+		t.Errorf("expected an unchanged report, got %+v", report)
+	}
+	if head := gittest.Output(t, ".", "rev-parse", "HEAD"); head != pushed {
+		t.Errorf("expected the pushed branch %s to be checked out, got %s", pushed, head)
+	}
+}
+
+func TestMergeScoped(t *testing.T) {
+	upstream, fork := newFork(t)
+	gittest.WriteFile(t, upstream, "packages/ext/index.ts", "export const a = 1\n")
+	gittest.WriteFile(t, upstream, "packages/other/index.ts", "export const b = 1\n")
+	gittest.Commit(t, upstream, "feat: add packages")
+
+	cfg := &config.Config{UpstreamURL: upstream, SyncPaths: []string{"packages/ext"}}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if _, err := sync.Merge(cfg, target, sync.DefaultBranch); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	gittest.Run(t, fork, "checkout", "-q", "main")
+	gittest.Run(t, fork, "merge", "-q", sync.DefaultBranch)
+
+	// Upstream churn outside the sync paths, including a conflicting change, is left out
+	gittest.WriteFile(t, fork, "src/extension.ts", "export const fork = 1\n")
+	gittest.Commit(t, fork, "feat: fork change")
+	gittest.WriteFile(t, upstream, "src/extension.ts", "export const upstream = 1\n")
+	gittest.WriteFile(t, upstream, "packages/other/index.ts", "export const b = 2\n")
+	gittest.Commit(t, upstream, "feat: unrelated change")
+
+	target, err = sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !report.UpToDate {
+		t.Errorf("expected no changes in the sync paths, got %+v", report)
+	}
+
+	gittest.WriteFile(t, upstream, "packages/ext/index.ts", "export const a = 2\n")
+	gittest.Commit(t, upstream, "feat: extension change")
+
+	target, err = sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err = sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !report.Merged || report.UpToDate || len(report.Conflicts) != 0 {
+		t.Fatalf("expected a clean scoped merge, got %+v", report)
+	}
+	if want := []string{"packages/other/index.ts", "src/extension.ts"}; !slices.Equal(report.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", report.Skipped, want)
+	}
+	for file, want := range map[string]string{
+		"packages/ext/index.ts": "export const a = 2\n",
+		"src/extension.ts":      "export const fork = 1\n",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(fork, file)); string(got) != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(fork, "packages/other")); !os.IsNotExist(err) {
+		t.Error("upstream package outside the sync paths was merged")
+	}
+	if !strings.Contains(gittest.Output(t, ".", "log", "-1", "--format=%P"), " ") {
+		t.Error("expected the scoped sync to be committed as a merge")
+	}
+
+	// Shared files at the root are merged along with the sync paths
+	gittest.WriteFile(t, upstream, "pnpm-lock.yaml", "lockfileVersion: '9.0'\n")
+	gittest.Commit(t, upstream, "chore: update lockfile")
+
+	target, err = sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err = sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !report.Merged || report.UpToDate {
+		t.Fatalf("expected the shared file change to be merged, got %+v", report)
+	}
+	if got, _ := os.ReadFile(filepath.Join(fork, "pnpm-lock.yaml")); string(got) != "lockfileVersion: '9.0'\n" {
+		t.Errorf("pnpm-lock.yaml = %q, want the upstream lockfile", got)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	upstream, fork := newFork(t)
+	gittest.WriteFile(t, upstream, "package.json", `{"name": "ext", "version": "1.1.0"}`)
+	gittest.WriteFile(t, upstream, "src/extension.ts", "export const upstream = 1\n")
+	gittest.Commit(t, upstream, "feat: upstream change")
+
+	gittest.WriteFile(t, fork, "package.json", `{"name": "ext-fork", "version": "1.0.0"}`)
+	gittest.WriteFile(t, fork, "src/extension.ts", "export const fork = 1\n")
+	gittest.Commit(t, fork, "feat: fork change")
+
+	cfg := &config.Config{}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if report.Merged {
+		t.Fatal("expected conflicts")
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Path != "src/extension.ts" || report.Conflicts[0].ForkManaged {
+		t.Errorf("unexpected conflicts %+v", report.Conflicts)
+	}
+	if len(report.Resolved) != 1 || report.Resolved[0].Path != "package.json" || report.Resolved[0].Policy != config.PolicyJSONFieldMerge {
+		t.Errorf("unexpected resolutions %+v", report.Resolved)
+	}
+
+	if len(report.ConflictCommits) != 1 || report.ConflictCommits[0].Subject != "feat: upstream change" {
+		t.Errorf("unexpected conflict commits %+v", report.ConflictCommits)
+	}
+
+	var md bytes.Buffer
+	report.WriteMarkdown(&md)
+	for _, want := range []string{"## Upstream sync conflicts", "| `src/extension.ts` | no |", "feat: upstream change", "- `package.json` (json-field-merge)"} {
+		if !bytes.Contains(md.Bytes(), []byte(want)) {
+			t.Errorf("markdown report does not contain %q:\n%s", want, md.String())
+		}
+	}
+
+	got, _ := os.ReadFile(filepath.Join(fork, "package.json"))
+	want := "{\n  \"name\": \"ext-fork\",\n  \"version\": \"1.1.0\"\n}\n"
+	if string(got) != want {
+		t.Errorf("package.json = %q, want %q", got, want)
+	}
+}
+
+func TestMergeJSONFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream string
+		fork     string
+		want     string // the merged package.json, or "" when it should stay in conflict
+	}{
+		{
+			name:     "fields changed on one side",
+			upstream: `{"name": "ext", "version": "1.1.0", "description": "Upstream"}`,
+			fork:     `{"name": "ext-fork", "version": "1.0.0", "dependencies": {"a": "^1.0.0"}}`,
+			want:     "{\n  \"name\": \"ext-fork\",\n  \"version\": \"1.1.0\",\n  \"description\": \"Upstream\",\n  \"dependencies\": {\n    \"a\": \"^1.0.0\"\n  }\n}\n",
+		},
+		{
+			name:     "field changed on both sides",
+			upstream: `{"name": "ext", "version": "1.1.0", "main": "out/main.js"}`,
+			fork:     `{"name": "ext-fork", "version": "1.0.0", "main": "dist/main.js"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, fork := newFork(t)
+			gittest.CommitFile(t, upstream, "package.json", tt.upstream, "feat: upstream change")
+			gittest.CommitFile(t, fork, "package.json", tt.fork, "feat: fork change")
+
+			cfg := &config.Config{}
+			target, err := sync.Fetch(cfg, upstream)
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+			if err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+
+			if tt.want == "" {
+				if report.Merged || len(report.Conflicts) != 1 || report.Conflicts[0].Path != "package.json" {
+					t.Errorf("expected package.json to stay in conflict, got %+v", report)
+				}
+				return
+			}
+			if !report.Merged {
+				t.Fatalf("expected a clean merge, got %+v", report)
+			}
+			if got, _ := os.ReadFile(filepath.Join(fork, "package.json")); string(got) != tt.want {
+				t.Errorf("package.json = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	upstream, fork := newFork(t)
+	gittest.WriteFile(t, upstream, "README.md", "# Upstream\n")
+	gittest.WriteFile(t, upstream, "src/extension.ts", "export const upstream = 1\n")
+	gittest.Commit(t, upstream, "docs: upstream readme")
+
+	gittest.WriteFile(t, fork, "README.md", "# Fork\n")
+	gittest.WriteFile(t, fork, "src/extension.ts", "export const fork = 1\n")
+	gittest.Commit(t, fork, "docs: fork readme")
+
+	cfg := &config.Config{ConflictPolicies: []config.ConflictPolicy{
+		{Path: "README.md", Policy: config.PolicyOurs},
+		{Path: "src/**", Policy: config.PolicyTheirs},
+	}}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err := sync.Merge(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if !report.Merged || len(report.Conflicts) != 0 || len(report.Resolved) != 2 {
+		t.Fatalf("expected merge resolved by policies, got %+v", report)
+	}
+	for file, want := range map[string]string{"README.md": "# Fork\n", "src/extension.ts": "export const upstream = 1\n"} {
+		if got, _ := os.ReadFile(filepath.Join(fork, file)); string(got) != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+}
+
+// recordPatch commits a fork change and adds it to the patch series.
+func recordPatch(t *testing.T, fork, file, content string) {
+	t.Helper()
+	gittest.WriteFile(t, fork, file, content)
+	gittest.Commit(t, fork, "feat: fork change")
+	gittest.Run(t, fork, "format-patch", "-q", "-1", "-o", config.PatchDir)
+	gittest.Commit(t, fork, "chore: record fork patch")
+}
+
+func TestRebuild(t *testing.T) {
+	upstream, fork := newFork(t)
+	recordPatch(t, fork, "src/extension.ts", "export const fork = 1\n")
+	orig := gittest.Output(t, ".", "rev-parse", "HEAD")
+
+	gittest.WriteFile(t, upstream, "NEW.md", "new\n")
+	gittest.Commit(t, upstream, "feat: add file")
+
+	cfg := &config.Config{UpstreamURL: upstream, SyncStrategy: config.SyncStrategyPatches}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err := sync.Rebuild(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if !report.Merged || len(report.Applied) != 1 || len(report.FailedPatches) != 0 {
+		t.Fatalf("expected the patch to apply, got %+v", report)
+	}
+	if parents := gittest.Output(t, ".", "log", "-1", "--format=%P"); parents != orig {
+		t.Errorf("expected a single commit on top of %s, got parents %s", orig, parents)
+	}
+	for file, want := range map[string]string{
+		"NEW.md":                    "new\n",
+		"src/extension.ts":          "export const fork = 1\n",
+		config.UpstreamRevisionPath: gittest.Output(t, ".", "rev-parse", "upstream/main") + "\n",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(fork, file)); string(got) != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+
+	// Syncing the same upstream revision again is a no-op
+	gittest.Run(t, fork, "checkout", "-q", "main")
+	gittest.Run(t, fork, "merge", "-q", "--ff-only", sync.DefaultBranch)
+	if report, err = sync.Rebuild(cfg, target, sync.DefaultBranch); err != nil || !report.UpToDate {
+		t.Fatalf("expected an up to date report, got %+v, %v", report, err)
+	}
+
+	gittest.WriteFile(t, upstream, "src/extension.ts", "export const upstream = 2\n")
+	gittest.Commit(t, upstream, "feat: change extension")
+	if target, err = sync.Fetch(cfg, upstream); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	report, err = sync.Rebuild(cfg, target, sync.DefaultBranch)
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if report.Merged || len(report.FailedPatches) != 1 || !strings.Contains(report.FailedPatches[0].Error, "src/extension.ts") {
+		t.Errorf("expected the patch to fail on src/extension.ts, got %+v", report)
+	}
+
+	var out bytes.Buffer
+	report.WriteMarkdown(&out)
+	if !strings.Contains(out.String(), report.FailedPatches[0].Patch) {
+		t.Errorf("markdown does not list the failing patch:\n%s", out.String())
+	}
+}
+
+func TestRebuildUnrecordedChanges(t *testing.T) {
+	upstream, fork := newFork(t)
+	recordPatch(t, fork, "src/extension.ts", "export const fork = 1\n")
+	gittest.WriteFile(t, fork, "FORK.md", "not in a patch\n")
+	gittest.Commit(t, fork, "docs: add fork notes")
+
+	gittest.WriteFile(t, upstream, "NEW.md", "new\n")
+	gittest.Commit(t, upstream, "feat: add file")
+
+	cfg := &config.Config{UpstreamURL: upstream, SyncStrategy: config.SyncStrategyPatches}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if _, err := sync.Rebuild(cfg, target, sync.DefaultBranch); err == nil || !strings.Contains(err.Error(), "FORK.md") {
+		t.Errorf("expected an error naming FORK.md, got %v", err)
+	}
+	if branch := gittest.Output(t, ".", "branch", "--show-current"); branch != "main" {
+		t.Errorf("expected to be back on main, got %q", branch)
+	}
+}
+
+func TestFetchTagMode(t *testing.T) {
+	upstream, _ := newFork(t)
+	for _, tag := range []string{"v1.2.0", "v1.10.0", "v1.11.0-rc.1", "v1.11.0-beta.2", "other-2.0.0"} {
+		gittest.Run(t, upstream, "tag", tag)
+	}
+
+	cfg := &config.Config{SyncMode: config.SyncModeTag}
+	target, err := sync.Fetch(cfg, upstream)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if target.Tag != "v1.10.0" || target.Ref != "refs/upstream-tags/v1.10.0" {
+		t.Errorf("unexpected target %+v", target)
+	}
+	if target.Title() != "chore: sync with upstream v1.10.0" {
+		t.Errorf("unexpected title %q", target.Title())
+	}
+
+	tags, err := sync.Tags("v*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.10.0", "v1.2.0"}; !slices.Equal(tags, want) {
+		t.Errorf("Tags() = %v, want %v", tags, want)
+	}
+}
+
+func TestForkManaged(t *testing.T) {
+	cfg := &config.Config{ExtensionPath: "./packages/ext"}
+	for file, want := range map[string]bool{
+		".github/workflows/ovsx-fork-tools-sync.yml": true,
+		".github/workflows/ci.yml":                   false,
+		".ovsx-fork/config.json":                     true,
+		"packages/ext/package.json":                  true,
+		"package.json":                               false,
+	} {
+		if got := sync.ForkManaged(cfg, file); got != want {
+			t.Errorf("ForkManaged(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
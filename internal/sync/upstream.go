@@ -0,0 +1,171 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/gh"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/git"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+// Remote is the name of the git remote pointing at the upstream repository.
+const Remote = "upstream"
+
+// Target is the upstream revision selected for merging.
+type Target struct {
+	// URL is the upstream repository URL.
+	URL string `json:"url"`
+	// Branch is the upstream branch being tracked.
+	Branch string `json:"branch"`
+	// Ref is the local ref that is merged, e.g. "upstream/main".
+	Ref string `json:"ref"`
+	// Tag is the upstream release tag being merged in tag mode.
+	Tag string `json:"tag,omitempty"`
+}
+
+// Title returns the commit and pull request title for merging the target.
+func (t *Target) Title() string {
+	if t.Tag != "" {
+		return "chore: sync with upstream " + t.Tag
+	}
+	return "chore: sync with upstream"
+}
+
+// DetectURL returns the configured upstream URL or, when none is configured,
+// the URL of the GitHub repository this one was forked from.
+func DetectURL(cfg *config.Config) (string, error) {
+	if cfg.UpstreamURL != "" {
+		return cfg.UpstreamURL, nil
+	}
+
+	parent, err := gh.Output("repo", "view", "--json", "parent", "--jq", `if .parent then (.parent.owner.login + "/" + .parent.name) else "" end`)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect the parent repository: %w", err)
+	}
+	if parent == "" {
+		return "", fmt.Errorf("this repository is not a fork; configure the upstream with 'ovsx-setup --upstream <url>'")
+	}
+
+	url, err := gh.Output("repo", "view", parent, "--json", "url", "--jq", ".url")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the URL of %s: %w", parent, err)
+	}
+	return url, nil
+}
+
+// Fetch points the upstream remote at url, fetches it, and selects the revision to merge
+// according to the configured sync mode.
+func Fetch(cfg *config.Config, url string) (*Target, error) {
+	if current, err := git.Output("remote", "get-url", Remote); err != nil {
+		if _, err := git.Output("remote", "add", Remote, url); err != nil {
+			return nil, err
+		}
+	} else if current != url {
+		if _, err := git.Output("remote", "set-url", Remote, url); err != nil {
+			return nil, err
+		}
+	}
+
+	// Upstream's tags are left out so they are not mistaken for the fork's release tags
+	if _, err := git.Output("fetch", "--no-tags", Remote); err != nil {
+		return nil, err
+	}
+
+	branch := cfg.UpstreamBranch
+	if branch == "" {
+		var err error
+		if branch, err = defaultBranch(); err != nil {
+			return nil, err
+		}
+	}
+
+	target := &Target{URL: url, Branch: branch, Ref: Remote + "/" + branch}
+	if cfg.SyncMode != config.SyncModeTag {
+		return target, nil
+	}
+
+	tag, err := latestTag(cfg.UpstreamTagPattern)
+	if err != nil {
+		return nil, err
+	}
+	target.Tag = tag
+	target.Ref = "refs/upstream-tags/" + tag
+	return target, nil
+}
+
+// defaultBranch asks the upstream remote which branch its HEAD points at.
+func defaultBranch() (string, error) {
+	lines, err := git.Lines("ls-remote", "--symref", Remote, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if ref, ok := strings.CutPrefix(line, "ref: refs/heads/"); ok {
+			return strings.Fields(ref)[0], nil
+		}
+	}
+	return "", fmt.Errorf("could not detect the default branch of %s", Remote)
+}
+
+// latestTag returns the highest upstream release tag matching pattern.
+func latestTag(pattern string) (string, error) {
+	tags, err := Tags(pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", fmt.Errorf("no upstream tag matches %q", pattern)
+	}
+	return tags[0], nil
+}
+
+// Tags fetches upstream's tags into refs/upstream-tags, so they never mix with the fork's
+// own release tags, and returns the release tags matching pattern from the highest version
+// down. Pre-release tags such as "v1.3.0-rc.1" are left out. An empty pattern uses
+// config.DefaultUpstreamTagPattern.
+func Tags(pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = config.DefaultUpstreamTagPattern
+	}
+	if _, err := git.Output("fetch", Remote, "--no-tags", "+refs/tags/*:refs/upstream-tags/*"); err != nil {
+		return nil, err
+	}
+	lines, err := git.Lines("for-each-ref", "--sort=-v:refname", "--format=%(refname:strip=2)", "refs/upstream-tags/"+pattern)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, t := range lines {
+		if v, err := TagVersion(t); err == nil && v.Pre != "" {
+			continue
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+// Base returns the upstream revision HEAD is based on: the revision recorded by the patches
+// sync strategy, or the merge base of HEAD and the target. recorded reports which one it is.
+func Base(cfg *config.Config, target *Target) (base string, recorded bool, err error) {
+	if cfg.SyncStrategy == config.SyncStrategyPatches {
+		// A rebuilt fork does not contain upstream's history, only the revision it was built from
+		if base, err := git.Output("show", "HEAD:"+config.UpstreamRevisionPath); err == nil {
+			return base, true, nil
+		}
+	}
+	base, err = git.Output("merge-base", "HEAD", target.Ref)
+	if err != nil {
+		return "", false, fmt.Errorf("the fork shares no history with %s: %w", target.Ref, err)
+	}
+	return base, false, nil
+}
+
+// TagVersion extracts the version from an upstream tag such as "v1.2.3" or "release/1.2.3".
+func TagVersion(tag string) (version.Version, error) {
+	if i := strings.LastIndexAny(tag, "/@"); i >= 0 {
+		tag = tag[i+1:]
+	}
+	return version.Parse(tag)
+}
@@ -0,0 +1,112 @@
+// Package tag renders release tag names from a configurable template.
+//
+// A template is a string containing placeholders in braces, for example
+// "{name}@{version}" or "fork/v{version}". The supported placeholders are:
+//
+//	{version}	The version being released (required).
+//	{name}		The "name" field of the extension's package.json.
+//	{path}		The extension path without leading "./" and trailing "/".
+package tag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// RootTemplate is the default template for extensions at the repository root.
+	RootTemplate = "v{version}"
+	// PathTemplate is the default template for extensions in a subdirectory.
+	PathTemplate = "{path}/v{version}"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+var placeholders = map[string]bool{
+	"{version}": true,
+	"{name}":    true,
+	"{path}":    true,
+}
+
+// Fields are the values substituted into a template.
+type Fields struct {
+	Name    string
+	Version string
+	Path    string
+}
+
+// CleanPath normalizes an extension path the same way the workflows do,
+// removing a leading "./" and a trailing "/".
+func CleanPath(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(path, "./"), "/")
+}
+
+// Default returns the template used when none is configured.
+// It matches the tags produced before templates were configurable.
+func Default(extensionPath string) string {
+	if extensionPath == "" || extensionPath == "." || extensionPath == "./" {
+		return RootTemplate
+	}
+	return PathTemplate
+}
+
+// Validate checks that the template only uses known placeholders, contains {version},
+// and renders to a valid git tag name for the extension at extensionPath. The path may
+// not be known yet (it can come from the workflow environment), so a root path is not
+// rejected here; see ValidatePath.
+func Validate(template, extensionPath string) error {
+	if template == "" {
+		return fmt.Errorf("tag template is empty")
+	}
+	for _, p := range placeholderPattern.FindAllString(template, -1) {
+		if !placeholders[p] {
+			return fmt.Errorf("tag template %q: unknown placeholder %s", template, p)
+		}
+	}
+	if !strings.Contains(template, "{version}") {
+		return fmt.Errorf("tag template %q must contain {version}", template)
+	}
+
+	if p := CleanPath(extensionPath); p == "" || p == "." {
+		extensionPath = "path"
+	}
+	sample := Render(template, Fields{Name: "name", Version: "1.0.0", Path: extensionPath})
+	if err := checkRefName(sample); err != nil {
+		return fmt.Errorf("tag template %q: %w", template, err)
+	}
+	return nil
+}
+
+// ValidatePath checks that a template using {path} is not rendered for an extension at
+// the repository root, where the placeholder is empty.
+func ValidatePath(template, extensionPath string) error {
+	if p := CleanPath(extensionPath); strings.Contains(template, "{path}") && (p == "" || p == ".") {
+		return fmt.Errorf("tag template %q uses {path}, but the extension is at the repository root", template)
+	}
+	return nil
+}
+
+// Render substitutes the fields into the template.
+func Render(template string, f Fields) string {
+	return strings.NewReplacer(
+		"{version}", f.Version,
+		"{name}", f.Name,
+		"{path}", CleanPath(f.Path),
+	).Replace(template)
+}
+
+// checkRefName applies the rules of git check-ref-format that a template can violate.
+func checkRefName(name string) error {
+	switch {
+	case strings.ContainsAny(name, " ~^:?*[\\{}"):
+		return fmt.Errorf("%q contains a character not allowed in tag names", name)
+	case strings.Contains(name, "..") || strings.Contains(name, "@{") || strings.Contains(name, "//"):
+		return fmt.Errorf("%q contains a sequence not allowed in tag names", name)
+	case strings.HasPrefix(name, "/") || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "."):
+		return fmt.Errorf("%q must not start with %q", name, name[:1])
+	case strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".") || strings.HasSuffix(name, ".lock"):
+		return fmt.Errorf("%q has an invalid ending", name)
+	}
+	return nil
+}
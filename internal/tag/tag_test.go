@@ -0,0 +1,96 @@
+package tag_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/tag"
+)
+
+func TestRender(t *testing.T) {
+	fields := tag.Fields{Name: "my-ext", Version: "1.2.3", Path: "./packages/ext/"}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{tag.RootTemplate, "v1.2.3"},
+		{tag.PathTemplate, "packages/ext/v1.2.3"},
+		{"{name}@{version}", "my-ext@1.2.3"},
+		{"fork/v{version}", "fork/v1.2.3"},
+	}
+
+	for _, tt := range tests {
+		if got := tag.Render(tt.template, fields); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	for path, want := range map[string]string{
+		"":           tag.RootTemplate,
+		".":          tag.RootTemplate,
+		"./":         tag.RootTemplate,
+		"./packages": tag.PathTemplate,
+	} {
+		if got := tag.Default(path); got != want {
+			t.Errorf("Default(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		wantErr  string
+	}{
+		{"v{version}", "", ""},
+		{"{name}@{version}", ".", ""},
+		{"{path}/v{version}", "./packages/ext/", ""},
+		{"fork/v{version}", "", ""},
+		{"", "", "empty"},
+		{"v1", "", "must contain {version}"},
+		{"{publisher}-{version}", "", "unknown placeholder {publisher}"},
+		{"release {version}", "", "not allowed"},
+		{"fork..{version}", "", "not allowed"},
+		{"-{version}", "", "must not start"},
+		{"{version}.lock", "", "invalid ending"},
+		{"{path}/v{version}", "", ""},
+		{"{path}-v{version}", ".", ""},
+		{"{path}/v{version}", "my ext", "not allowed"},
+	}
+
+	for _, tt := range tests {
+		err := tag.Validate(tt.template, tt.path)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("Validate(%q) unexpected error: %v", tt.template, err)
+		case tt.wantErr != "" && err == nil:
+			t.Errorf("Validate(%q) expected error containing %q, got nil", tt.template, tt.wantErr)
+		case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+			t.Errorf("Validate(%q) = %v, want error containing %q", tt.template, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		wantErr  bool
+	}{
+		{"{path}/v{version}", "packages/ext", false},
+		{"v{version}", "", false},
+		{"{path}/v{version}", "", true},
+		{"{path}/v{version}", "./", true},
+		{"{path}-v{version}", ".", true},
+	}
+
+	for _, tt := range tests {
+		if err := tag.ValidatePath(tt.template, tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePath(%q, %q) = %v, want error %t", tt.template, tt.path, err, tt.wantErr)
+		}
+	}
+}
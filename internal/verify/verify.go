@@ -0,0 +1,162 @@
+// Package verify implements the verify command, which checks that a published version
+// reached the registry intact.
+//
+// Usage:
+//
+//	ovsx-setup verify [--vsix <file>] [--version <version>] [--target <platform>] [--registry <url>] [--timeout <duration>]
+//
+// 'ovsx publish' returns before the registry has finished processing the upload, and an
+// upload can still be rejected afterwards. verify polls the registry until the extension's
+// version is listed, then compares the SHA-256 of the published package with the local
+// .vsix, of the given target platform when the extension is packaged per platform. It
+// fails when the version does not appear in time or the checksums differ.
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/config"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/manifest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+)
+
+// Options selects the published version to verify and how long to wait for it.
+type Options struct {
+	Namespace, Name, Version string
+	// Target is the package's target platform; empty or "universal" for the universal package.
+	Target string
+	// Vsix is the locally packaged file.
+	Vsix     string
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+func Run(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	vsix := fs.String("vsix", "", "The packaged .vsix (default: the one in the extension directory)")
+	ver := fs.String("version", "", "The published version (default: the version in the extension's package.json)")
+	target := fs.String("target", "", "The package's target platform, e.g. 'linux-x64' (default: universal)")
+	registry := fs.String("registry", openvsx.DefaultURL, "OpenVSX registry the extension was published to")
+	timeout := fs.Duration("timeout", 5*time.Minute, "How long to wait for the registry to list the version")
+	interval := fs.Duration("interval", 10*time.Second, "How often to poll the registry")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+	cfg.ApplyEnv()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m, err := manifest.Read(cfg.ExtensionDir())
+	if err != nil {
+		return err
+	}
+	opts := Options{Namespace: cfg.Publisher, Name: m.Name, Version: *ver, Target: *target, Vsix: *vsix, Timeout: *timeout, Interval: *interval}
+	if opts.Namespace == "" {
+		opts.Namespace = m.Publisher
+	}
+	if opts.Version == "" {
+		opts.Version = m.Version
+	}
+	if opts.Target == config.TargetUniversal {
+		opts.Target = ""
+	}
+	if opts.Vsix == "" {
+		if opts.Vsix, err = findVsix(cfg.ExtensionDir(), m, opts.Target); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Waiting for %s.%s %s on %s\n", opts.Namespace, opts.Name, opts.Version, *registry)
+	sum, err := Verify(openvsx.New(*registry), opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Verified %s.%s %s (sha256 %s)\n", opts.Namespace, opts.Name, opts.Version, sum)
+	return nil
+}
+
+// Verify waits for the version to be listed by the registry and checks that the published
+// package matches the local one. It returns the package's SHA-256.
+func Verify(client *openvsx.Client, opts Options) (string, error) {
+	local, err := FileSHA256(opts.Vsix)
+	if err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(opts.Timeout)
+	var ext *openvsx.Extension
+	for {
+		ext, err = client.ExtensionTarget(opts.Namespace, opts.Name, opts.Target, opts.Version)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, openvsx.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Registry lookup failed, retrying: %v\n", err)
+		}
+		if time.Now().Add(opts.Interval).After(deadline) {
+			return "", fmt.Errorf("%s.%s %s was not listed by the registry within %v: %w", opts.Namespace, opts.Name, opts.Version, opts.Timeout, err)
+		}
+		time.Sleep(opts.Interval)
+	}
+
+	published, err := client.SHA256(ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the checksum of the published package: %w", err)
+	}
+	if published != local {
+		return "", fmt.Errorf("the published package of %s.%s %s does not match %s (sha256 %s, local %s)", opts.Namespace, opts.Name, opts.Version, opts.Vsix, published, local)
+	}
+	return local, nil
+}
+
+// findVsix returns the package 'vsce package' wrote to dir: its default name
+// <name>[-<target>]-<version>.vsix, or the only .vsix in dir.
+func findVsix(dir string, m *manifest.Manifest, target string) (string, error) {
+	name := m.Name
+	if target != "" {
+		name += "-" + target
+	}
+	def := filepath.Join(dir, fmt.Sprintf("%s-%s.vsix", name, m.Version))
+	if _, err := os.Stat(def); err == nil {
+		return def, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.vsix"))
+	if err != nil {
+		return "", err
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no .vsix found in %s; package the extension or pass --vsix", dir)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("several .vsix files in %s; pass --vsix", dir)
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 digest of the named file.
+func FileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,85 @@
+package verify_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/openvsx"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/verify"
+)
+
+func TestVerify(t *testing.T) {
+	pkg := []byte("vsix contents")
+	sum := sha256.Sum256(pkg)
+	vsix := filepath.Join(t.TempDir(), "ext-1.0.0.vsix")
+	if err := os.WriteFile(vsix, pkg, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		version   string
+		published []byte
+		checksum  bool
+		wantErr   string
+	}{
+		{name: "checksum file", version: "1.0.0", published: pkg, checksum: true},
+		{name: "hashed download", version: "1.0.0", published: pkg},
+		{name: "mismatch", version: "1.0.0", published: []byte("tampered"), wantErr: "does not match"},
+		{name: "timeout", version: "2.0.0", published: pkg, wantErr: "was not listed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups atomic.Int32
+			var srv *httptest.Server
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/pub/ext/1.0.0":
+					// The registry lists the version only after processing the upload
+					if lookups.Add(1) < 3 {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					files := `"download": "` + srv.URL + `/ext.vsix"`
+					if tt.checksum {
+						files += `, "sha256": "` + srv.URL + `/ext.sha256"`
+					}
+					w.Write([]byte(`{"namespace": "pub", "name": "ext", "version": "1.0.0", "files": {` + files + `}}`))
+				case "/ext.vsix":
+					w.Write(tt.published)
+				case "/ext.sha256":
+					s := sha256.Sum256(tt.published)
+					w.Write([]byte(strings.ToUpper(hex.EncodeToString(s[:])) + "  ext-1.0.0.vsix\n"))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			got, err := verify.Verify(openvsx.New(srv.URL), verify.Options{
+				Namespace: "pub", Name: "ext", Version: tt.version, Vsix: vsix,
+				Timeout: 200 * time.Millisecond, Interval: 10 * time.Millisecond,
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if got != hex.EncodeToString(sum[:]) {
+				t.Errorf("unexpected checksum %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Package version parses extension versions and maps upstream versions to fork versions.
+//
+// OpenVSX refuses to republish a version, so a fork that needs to release its own
+// changes without an upstream bump must publish a version upstream never uses.
+// The revision scheme does this by widening the patch number and offsetting it by one:
+// with three revision digits, upstream 1.2.3 is released as 1.2.4000 and fork revisions
+// of it as 1.2.4001, 1.2.4002, and so on. The offset keeps upstream x.y.0 (x.y.1000)
+// and its revisions from colliding with upstream's own x.y.0 and x.y.1. Mapped versions
+// keep upstream's ordering, since upstream 1.2.4 becomes 1.2.5000, which is greater than
+// any revision of 1.2.3.
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SchemeUpstream publishes upstream's version unchanged.
+	SchemeUpstream = "upstream"
+	// SchemeRevision publishes upstream versions with room for fork revisions.
+	SchemeRevision = "revision"
+
+	// DefaultRevisionDigits is the number of patch digits reserved for revisions.
+	DefaultRevisionDigits = 3
+)
+
+// Version is a semantic version as used by VS Code extensions.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the pre-release suffix without the leading "-", if any.
+	Pre string
+}
+
+// Parse parses a version of the form MAJOR.MINOR.PATCH[-PRE], with an optional leading "v".
+func Parse(s string) (Version, error) {
+	var v Version
+	core := strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(core, '+'); i >= 0 {
+		core = core[:i]
+	}
+	if i := strings.IndexByte(core, '-'); i >= 0 {
+		core, v.Pre = core[:i], core[i+1:]
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is lower than, equal to, or greater than o.
+// Pre-release identifiers are compared as plain strings after the release they precede.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			if d < 0 {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	case v.Pre < o.Pre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// ValidateScheme checks that scheme is a known version scheme.
+// The empty string selects the upstream scheme.
+func ValidateScheme(scheme string) error {
+	switch scheme {
+	case "", SchemeUpstream, SchemeRevision:
+		return nil
+	}
+	return fmt.Errorf("unknown version scheme %q (expected %q or %q)", scheme, SchemeUpstream, SchemeRevision)
+}
+
+// Fork returns the fork version for revision rev of upstream, reserving digits patch digits for revisions.
+func Fork(upstream Version, rev, digits int) (Version, error) {
+	limit := pow10(digits)
+	if rev < 0 || rev >= limit {
+		return Version{}, fmt.Errorf("revision %d of %s does not fit in %d digits", rev, upstream, digits)
+	}
+	v := upstream
+	v.Patch = (upstream.Patch+1)*limit + rev
+	return v, nil
+}
+
+// Upstream splits a fork version into the upstream version and revision it was derived from.
+func Upstream(fork Version, digits int) (Version, int, error) {
+	limit := pow10(digits)
+	if fork.Patch < limit {
+		return Version{}, 0, fmt.Errorf("%s is not a fork version with %d revision digits", fork, digits)
+	}
+	v := fork
+	v.Patch = fork.Patch/limit - 1
+	return v, fork.Patch % limit, nil
+}
+
+func pow10(n int) int {
+	p := 1
+	for range n {
+		p *= 10
+	}
+	return p
+}
@@ -0,0 +1,88 @@
+package version_test
+
+import (
+	"testing"
+
+	"github.com/timsexperiments/ovsx-fork-tools/internal/version"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "1.2.3", want: "1.2.3"},
+		{in: "v1.2.3", want: "1.2.3"},
+		{in: "1.2.3-beta.1", want: "1.2.3-beta.1"},
+		{in: "1.2.3+build", want: "1.2.3"},
+		{in: "1.2", wantErr: true},
+		{in: "1.x.3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		v, err := version.Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil || v.String() != tt.want {
+			t.Errorf("Parse(%q) = %v, %v, want %s", tt.in, v, err, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.9", 1},
+		{"1.2.3-beta", "1.2.3", -1},
+		{"1.2.3-alpha", "1.2.3-beta", -1},
+		{"1.2.4000", "1.2.3001", 1},
+	}
+
+	for _, tt := range tests {
+		a, _ := version.Parse(tt.a)
+		b, _ := version.Parse(tt.b)
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFork(t *testing.T) {
+	upstream, _ := version.Parse("1.2.3")
+
+	v, err := version.Fork(upstream, 1, 3)
+	if err != nil || v.String() != "1.2.4001" {
+		t.Fatalf("Fork = %v, %v, want 1.2.4001", v, err)
+	}
+
+	back, rev, err := version.Upstream(v, 3)
+	if err != nil || back.String() != "1.2.3" || rev != 1 {
+		t.Errorf("Upstream(%s) = %s, %d, %v, want 1.2.3, 1", v, back, rev, err)
+	}
+
+	if _, err := version.Fork(upstream, 1000, 3); err == nil {
+		t.Error("expected error for revision that does not fit")
+	}
+
+	// Upstream x.y.0 must not be released under upstream's own x.y.0 or x.y.1
+	zero, _ := version.Parse("1.3.0")
+	for rev := range 2 {
+		v, err := version.Fork(zero, rev, 3)
+		if err != nil || v.Compare(version.Version{Major: 1, Minor: 3, Patch: 1}) <= 0 {
+			t.Errorf("Fork(1.3.0, %d) = %v, %v, want a version above 1.3.1", rev, v, err)
+		}
+	}
+
+	if _, _, err := version.Upstream(version.Version{Major: 1, Minor: 3, Patch: 1}, 3); err == nil {
+		t.Error("expected error for a version that is not a fork version")
+	}
+}
@@ -3,22 +3,69 @@
 // Usage:
 //
 //	ovsx-setup -p <publisher> -e <extension_path>
+//	ovsx-setup <command> [flags]
 //
 // Options:
 //
 //	-p <publisher>	The publisher name for the extension.
 //	-e <extension_path>	The path to the extension relative to the cwd.
+//
+// Commands:
+//
+//	bump	Compute the next fork version and its release tag.
+//	sync	Merge upstream into the sync branch and report conflicts.
+//	pr-body	Describe the upstream changes a sync pull request brings in.
+//	diverge	Report how far the fork has drifted from upstream.
+//	verify	Check that a published version reached OpenVSX intact.
+//	backfill	Publish upstream releases missing from OpenVSX.
+//	doctor	Check the configuration and the secrets the workflows need.
+//	channel	Tell whether a release is published as a pre-release.
+//	release-notes	Write the GitHub Release body from upstream's release notes.
+//	attest	Write or verify the provenance statement of a .vsix.
+//	check-version	Tell whether merging a pull request releases a new version.
 package main
 
 import (
 	"fmt"
 	"os"
 
+	"github.com/timsexperiments/ovsx-fork-tools/internal/attest"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/backfill"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/bump"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/channel"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/checkversion"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/diverge"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/doctor"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/notes"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/prbody"
 	app "github.com/timsexperiments/ovsx-fork-tools/internal/setup"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/sync"
+	"github.com/timsexperiments/ovsx-fork-tools/internal/verify"
 )
 
+var commands = map[string]func(args []string) error{
+	"bump":          bump.Run,
+	"sync":          sync.Run,
+	"pr-body":       prbody.Run,
+	"diverge":       diverge.Run,
+	"verify":        verify.Run,
+	"backfill":      backfill.Run,
+	"doctor":        doctor.Run,
+	"channel":       channel.Run,
+	"release-notes": notes.Run,
+	"attest":        attest.Run,
+	"check-version": checkversion.Run,
+}
+
 func main() {
-	if err := app.Run(); err != nil {
+	run := func() error { return app.Run() }
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			run = func() error { return cmd(os.Args[2:]) }
+		}
+	}
+
+	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -74,6 +74,8 @@ func TestRealE2E(t *testing.T) {
 		runCommand(t, forkDir, "gh", "pr", "merge", fmt.Sprintf("%d", prNumber), "--merge", "--admin")
 	case "MERGED":
 		t.Logf("PR #%d was already merged (likely by auto-merge).", prNumber)
+		// The sync workflow should dispatch the release itself; the release workflow skips tags
+		// that already exist, so dispatching it here as well is harmless
 		t.Log("Triggering release workflow manually since bot merge doesn't trigger it...")
 		runCommand(t, forkDir, "gh", "workflow", "run", "ovsx-fork-tools-release.yml", "--repo", forkRepo, "--ref", "main")
 	}
@@ -257,7 +259,7 @@ func runOvsxSetup(t *testing.T, forkDir string) {
 	runCommand(t, projectRoot, "go", "build", "-o", setupBin, ".")
 	defer os.Remove(setupBin)
 
-	cmd := exec.Command(setupBin, "--extension-path", ".", "--publisher", "TimsExperiments")
+	cmd := exec.Command(setupBin, "--extension-path", ".", "--publisher", "TimsExperiments", "--auto-merge", "--dispatch-release")
 	cmd.Dir = forkDir
 	output, err := cmd.CombinedOutput()
 	if err != nil {